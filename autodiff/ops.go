@@ -0,0 +1,178 @@
+// Copyright 2023 Changkun Ou <changkun.de>. All rights reserved.
+// Use of this source code is governed by a MIT license that
+// can be found in the LICENSE file.
+
+package autodiff
+
+import (
+	stdmath "math"
+
+	"changkun.de/x/gogpu/math"
+)
+
+// Mul records the matrix multiplication v*u, whose forward and backward
+// multiplications run by the Mul of the tape.
+func (v *Var[T]) Mul(u *Var[T]) *Var[T] {
+	t := v.tape
+	a, b := v.Value, u.Value
+	return t.Op(t.mul(a, b), func(g math.Mat[T]) []math.Mat[T] {
+		return []math.Mat[T]{
+			t.mul(g, transpose(b)),
+			t.mul(transpose(a), g),
+		}
+	}, v, u)
+}
+
+// Add records the element-wise sum v+u, which broadcasts as
+// math.Mat[T].Add.
+func (v *Var[T]) Add(u *Var[T]) *Var[T] {
+	a, b := v.Value, u.Value
+	return v.tape.Op(a.Add(b), func(g math.Mat[T]) []math.Mat[T] {
+		return []math.Mat[T]{unbroadcast(g, a), unbroadcast(g, b)}
+	}, v, u)
+}
+
+// Sub records the element-wise difference v-u, which broadcasts as
+// math.Mat[T].Sub.
+func (v *Var[T]) Sub(u *Var[T]) *Var[T] {
+	a, b := v.Value, u.Value
+	return v.tape.Op(a.Sub(b), func(g math.Mat[T]) []math.Mat[T] {
+		return []math.Mat[T]{unbroadcast(g, a), unbroadcast(g.Scale(-1), b)}
+	}, v, u)
+}
+
+// Hadamard records the element-wise product v∘u, which broadcasts as
+// math.Mat[T].Hadamard.
+func (v *Var[T]) Hadamard(u *Var[T]) *Var[T] {
+	a, b := v.Value, u.Value
+	return v.tape.Op(a.Hadamard(b), func(g math.Mat[T]) []math.Mat[T] {
+		return []math.Mat[T]{unbroadcast(g.Hadamard(b), a), unbroadcast(g.Hadamard(a), b)}
+	}, v, u)
+}
+
+// Scale records the product s*v with a constant s.
+func (v *Var[T]) Scale(s T) *Var[T] {
+	return v.tape.Op(v.Value.Scale(s), func(g math.Mat[T]) []math.Mat[T] {
+		return []math.Mat[T]{g.Scale(s)}
+	}, v)
+}
+
+// T records the transpose of v.
+func (v *Var[T]) T() *Var[T] {
+	return v.tape.Op(transpose(v.Value), func(g math.Mat[T]) []math.Mat[T] {
+		return []math.Mat[T]{transpose(g)}
+	}, v)
+}
+
+// Sum records the sum of all elements of v as a 1x1 matrix.
+func (v *Var[T]) Sum() *Var[T] {
+	a := v.Value
+	s := math.Mat[T]{Row: 1, Col: 1, Data: []T{a.Sum()}}
+	return v.tape.Op(s, func(g math.Mat[T]) []math.Mat[T] {
+		return []math.Mat[T]{math.Zeros[T](a.Row, a.Col).Add(g)}
+	}, v)
+}
+
+// Mean records the arithmetic mean of all elements of v as a 1x1
+// matrix.
+func (v *Var[T]) Mean() *Var[T] {
+	n := T(v.Value.Row * v.Value.Col)
+	return v.Sum().Scale(1 / n)
+}
+
+// SumAxis records the sums of v along the given axis, see
+// math.Mat[T].SumAxis.
+func (v *Var[T]) SumAxis(axis int) *Var[T] {
+	a := v.Value
+	return v.tape.Op(a.SumAxis(axis), func(g math.Mat[T]) []math.Mat[T] {
+		return []math.Mat[T]{math.Zeros[T](a.Row, a.Col).Add(g)}
+	}, v)
+}
+
+// MeanAxis records the arithmetic means of v along the given axis, see
+// math.Mat[T].MeanAxis.
+func (v *Var[T]) MeanAxis(axis int) *Var[T] {
+	n := v.Value.Row
+	if axis == 1 {
+		n = v.Value.Col
+	}
+	return v.SumAxis(axis).Scale(1 / T(n))
+}
+
+// Apply records the element-wise function f of v, whose derivative df
+// receives an element x of v and the element y = f(x) of the result.
+func (v *Var[T]) Apply(f func(x T) T, df func(x, y T) T) *Var[T] {
+	a := v.Value
+	y := a.Apply(f)
+	return v.tape.Op(y, func(g math.Mat[T]) []math.Mat[T] {
+		d := math.Mat[T]{Row: a.Row, Col: a.Col, Data: make([]T, a.Row*a.Col)}
+		for i := 0; i < a.Row; i++ {
+			for j := 0; j < a.Col; j++ {
+				k := i*a.Col + j
+				d.Data[k] = g.Get(i, j) * df(a.Get(i, j), y.Data[k])
+			}
+		}
+		return []math.Mat[T]{d}
+	}, v)
+}
+
+// ReLU records the rectified linear unit max(0, x) of v.
+func (v *Var[T]) ReLU() *Var[T] {
+	return v.Apply(func(x T) T {
+		if x > 0 {
+			return x
+		}
+		return 0
+	}, func(x, y T) T {
+		if x > 0 {
+			return 1
+		}
+		return 0
+	})
+}
+
+// Sigmoid records the logistic function 1/(1+exp(-x)) of v.
+func (v *Var[T]) Sigmoid() *Var[T] {
+	return v.Apply(func(x T) T {
+		return T(1 / (1 + stdmath.Exp(-float64(x))))
+	}, func(x, y T) T { return y * (1 - y) })
+}
+
+// Tanh records the hyperbolic tangent of v.
+func (v *Var[T]) Tanh() *Var[T] {
+	return v.Apply(func(x T) T {
+		return T(stdmath.Tanh(float64(x)))
+	}, func(x, y T) T { return 1 - y*y })
+}
+
+// Exp records the exponential of v.
+func (v *Var[T]) Exp() *Var[T] {
+	return v.Apply(func(x T) T {
+		return T(stdmath.Exp(float64(x)))
+	}, func(x, y T) T { return y })
+}
+
+// Log records the natural logarithm of v.
+func (v *Var[T]) Log() *Var[T] {
+	return v.Apply(func(x T) T {
+		return T(stdmath.Log(float64(x)))
+	}, func(x, y T) T { return 1 / x })
+}
+
+// unbroadcast sums the gradient g of a broadcast result over the
+// dimensions along which the operand a was stretched.
+func unbroadcast[T math.Float](g, a math.Mat[T]) math.Mat[T] {
+	if a.Row == 1 && g.Row != 1 {
+		g = g.SumAxis(0)
+	}
+	if a.Col == 1 && g.Col != 1 {
+		g = g.SumAxis(1)
+	}
+	return g
+}
+
+// transpose returns a dense transpose of m, which can be multiplied on
+// the GPU.
+func transpose[T math.Float](m math.Mat[T]) math.Mat[T] {
+	return m.View().T().Clone()
+}
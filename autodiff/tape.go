@@ -0,0 +1,183 @@
+// Copyright 2023 Changkun Ou <changkun.de>. All rights reserved.
+// Use of this source code is governed by a MIT license that
+// can be found in the LICENSE file.
+
+package autodiff
+
+import (
+	"fmt"
+	stdmath "math"
+
+	"changkun.de/x/gogpu/math"
+)
+
+// Tape records the operations on variables for reverse-mode automatic
+// differentiation. Every operation computes its value immediately and
+// appends itself to the tape, hence the tape is in topological order
+// and Backward walks it in reverse.
+//
+// The zero value is an empty tape that multiplies on the CPU. A tape is
+// not safe for concurrent use.
+type Tape[T math.Float] struct {
+	// Mul multiplies the matrices of both the forward and the backward
+	// pass, e.g. gpu.Mul[T]. A nil Mul multiplies by math.Mat[T].Mul.
+	Mul math.MulFunc[T]
+	// NoGrad marks a tape that only evaluates its values, e.g. for a
+	// prediction, hence its variables need not be kept for a backward
+	// pass. Backward panics on such a tape.
+	NoGrad bool
+
+	vars []*Var[T]
+}
+
+// Var is a variable on a tape, which is either a leaf created by
+// Tape.Var or the result of an operation.
+type Var[T math.Float] struct {
+	// Value is the value of the variable.
+	Value math.Mat[T]
+	// Grad is the gradient of the output of the last Backward with
+	// respect to Value, which has the shape of Value.
+	Grad math.Mat[T]
+
+	tape   *Tape[T]
+	inputs []*Var[T]
+	back   func(grad math.Mat[T]) []math.Mat[T]
+}
+
+// Var returns a leaf variable of the given value. The value is not
+// copied, hence a parameter updated in place is seen by the next
+// forward pass.
+func (t *Tape[T]) Var(m math.Mat[T]) *Var[T] {
+	v := &Var[T]{Value: m, tape: t}
+	t.vars = append(t.vars, v)
+	return v
+}
+
+// Op records a custom operation of the given inputs and its value.
+// back returns the gradients with respect to the values of the inputs,
+// in order, from the gradient with respect to value. A nil gradient
+// means that the input does not contribute to the value.
+//
+// Op allows to record an operation whose gradient is cheaper to compute
+// directly than from the gradients of its building blocks.
+func (t *Tape[T]) Op(value math.Mat[T], back func(grad math.Mat[T]) []math.Mat[T], inputs ...*Var[T]) *Var[T] {
+	for _, in := range inputs {
+		if in.tape != t {
+			panic("autodiff: variables of different tapes")
+		}
+	}
+	v := &Var[T]{Value: value, tape: t, inputs: inputs, back: back}
+	t.vars = append(t.vars, v)
+	return v
+}
+
+// Tape returns the tape on which the variable is recorded.
+func (v *Var[T]) Tape() *Tape[T] { return v.tape }
+
+// Leaf returns true if the variable is not the result of an operation.
+func (v *Var[T]) Leaf() bool { return v.back == nil }
+
+// Backward computes the gradients of the sum of the elements of v with
+// respect to all variables on its tape. A variable on which v does not
+// depend has a zero gradient.
+func (v *Var[T]) Backward() {
+	t := v.tape
+	if t.NoGrad {
+		panic("autodiff: backward pass of a tape without gradients")
+	}
+	for _, u := range t.vars {
+		u.Grad = math.Zeros[T](u.Value.Row, u.Value.Col)
+	}
+	for i := range v.Grad.Data {
+		v.Grad.Data[i] = 1
+	}
+
+	// Only the variables up to v contribute to it.
+	end := len(t.vars) - 1
+	for t.vars[end] != v {
+		end--
+	}
+	for i := end; i >= 0; i-- {
+		u := t.vars[i]
+		if u.back == nil {
+			continue
+		}
+		grads := u.back(u.Grad)
+		for k, in := range u.inputs {
+			g := grads[k]
+			if g.Data == nil {
+				continue
+			}
+			if g.Row != in.Grad.Row || g.Col != in.Grad.Col {
+				panic(fmt.Sprintf("autodiff: gradient of shape %dx%d for a %dx%d variable",
+					g.Row, g.Col, in.Grad.Row, in.Grad.Col))
+			}
+			for r := 0; r < g.Row; r++ {
+				for c := 0; c < g.Col; c++ {
+					in.Grad.Data[r*g.Col+c] += g.Get(r, c)
+				}
+			}
+		}
+	}
+}
+
+// mul multiplies two matrices by the multiplication of the tape.
+func (t *Tape[T]) mul(m, n math.Mat[T]) math.Mat[T] {
+	if t.Mul == nil {
+		return m.Mul(n)
+	}
+	return t.Mul(m, n)
+}
+
+// CheckGrad compares the gradients computed by Backward with central
+// finite differences of step eps. The function f records its output
+// from the leaves xs on the given tape, and the gradients are those of
+// the sum of the elements of the output with respect to xs.
+//
+// It returns an error that describes the first element of a gradient
+// whose absolute and relative error both exceed tol. The values of xs
+// are not modified.
+func CheckGrad[T math.Float](f func(t *Tape[T], xs []*Var[T]) *Var[T], xs []math.Mat[T], eps, tol float64) error {
+	ms := make([]math.Mat[T], len(xs))
+	for i, x := range xs {
+		ms[i] = x.Clone()
+	}
+	eval := func() (*Tape[T], []*Var[T], *Var[T]) {
+		t := &Tape[T]{}
+		vs := make([]*Var[T], len(ms))
+		for i, m := range ms {
+			vs[i] = t.Var(m)
+		}
+		return t, vs, f(t, vs)
+	}
+	sum := func() float64 {
+		_, _, out := eval()
+		s := 0.0
+		for _, v := range out.Value.Clone().Data {
+			s += float64(v)
+		}
+		return s
+	}
+
+	_, vs, out := eval()
+	out.Backward()
+	for i, m := range ms {
+		for k, x := range m.Data {
+			m.Data[k] = x + T(eps)
+			plus := sum()
+			m.Data[k] = x - T(eps)
+			minus := sum()
+			m.Data[k] = x
+
+			want := (plus - minus) / (2 * eps)
+			got := float64(vs[i].Grad.Data[k])
+			diff := stdmath.Abs(got - want)
+			scale := stdmath.Max(stdmath.Abs(got), stdmath.Abs(want))
+			if diff > tol && diff > tol*scale || stdmath.IsNaN(diff) {
+				return fmt.Errorf("autodiff: gradient of input %d at (%d, %d) is %v, finite difference is %v",
+					i, k/m.Col, k%m.Col, got, want)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+// Copyright 2023 Changkun Ou <changkun.de>. All rights reserved.
+// Use of this source code is governed by a MIT license that
+// can be found in the LICENSE file.
+
+package main_test
+
+import (
+	"testing"
+
+	"changkun.de/x/gogpu/autodiff"
+	"changkun.de/x/gogpu/gpu"
+	"changkun.de/x/gogpu/math"
+)
+
+type graph = func(t *autodiff.Tape[float64], xs []*autodiff.Var[float64]) *autodiff.Var[float64]
+
+func TestCheckGrad(t *testing.T) {
+	a := math.NewRandMat[float64](3, 4, math.Normal(0, 1), math.WithSeed(1))
+	b := math.NewRandMat[float64](4, 2, math.Normal(0, 1), math.WithSeed(2))
+	c := math.NewRandMat[float64](3, 4, math.Normal(0, 1), math.WithSeed(3))
+	row := math.NewRandMat[float64](1, 4, math.Normal(0, 1), math.WithSeed(4))
+	col := math.NewRandMat[float64](3, 1, math.Normal(0, 1), math.WithSeed(5))
+	pos := math.NewRandMat[float64](3, 4, math.Uniform(0.5, 2), math.WithSeed(6))
+
+	for _, tt := range []struct {
+		name string
+		f    graph
+		xs   []math.Mat[float64]
+	}{
+		{"Mul", func(t *autodiff.Tape[float64], x []*autodiff.Var[float64]) *autodiff.Var[float64] {
+			return x[0].Mul(x[1])
+		}, []math.Mat[float64]{a, b}},
+		{"Add", func(t *autodiff.Tape[float64], x []*autodiff.Var[float64]) *autodiff.Var[float64] {
+			return x[0].Add(x[1]).Hadamard(x[0])
+		}, []math.Mat[float64]{a, c}},
+		{"AddRow", func(t *autodiff.Tape[float64], x []*autodiff.Var[float64]) *autodiff.Var[float64] {
+			return x[0].Add(x[1]).Hadamard(x[0])
+		}, []math.Mat[float64]{a, row}},
+		{"SubCol", func(t *autodiff.Tape[float64], x []*autodiff.Var[float64]) *autodiff.Var[float64] {
+			return x[1].Sub(x[0]).Hadamard(x[0])
+		}, []math.Mat[float64]{a, col}},
+		{"Hadamard", func(t *autodiff.Tape[float64], x []*autodiff.Var[float64]) *autodiff.Var[float64] {
+			return x[0].Hadamard(x[1]).Hadamard(x[2])
+		}, []math.Mat[float64]{a, row, col}},
+		{"ScaleT", func(t *autodiff.Tape[float64], x []*autodiff.Var[float64]) *autodiff.Var[float64] {
+			return x[0].T().Scale(-2.5).Mul(x[0])
+		}, []math.Mat[float64]{a}},
+		{"Reductions", func(t *autodiff.Tape[float64], x []*autodiff.Var[float64]) *autodiff.Var[float64] {
+			s0 := x[0].SumAxis(0).Hadamard(x[0].MeanAxis(0))
+			s1 := x[0].SumAxis(1).Hadamard(x[0].MeanAxis(1))
+			return s0.Sum().Hadamard(s1.Mean()).Add(x[0].Hadamard(x[0]).Mean())
+		}, []math.Mat[float64]{a}},
+		{"Activations", func(t *autodiff.Tape[float64], x []*autodiff.Var[float64]) *autodiff.Var[float64] {
+			return x[0].ReLU().Add(x[0].Sigmoid()).Add(x[0].Tanh()).Hadamard(x[0].Exp())
+		}, []math.Mat[float64]{a}},
+		{"Log", func(t *autodiff.Tape[float64], x []*autodiff.Var[float64]) *autodiff.Var[float64] {
+			return x[0].Log().Hadamard(x[0])
+		}, []math.Mat[float64]{pos}},
+		{"MLP", func(t *autodiff.Tape[float64], x []*autodiff.Var[float64]) *autodiff.Var[float64] {
+			h := x[0].Mul(x[1]).Tanh()
+			return h.Hadamard(h).Mean()
+		}, []math.Mat[float64]{c, b}},
+		{"Op", func(t *autodiff.Tape[float64], x []*autodiff.Var[float64]) *autodiff.Var[float64] {
+			// A custom square of the elements.
+			sq := t.Op(x[0].Value.Hadamard(x[0].Value), func(g math.Mat[float64]) []math.Mat[float64] {
+				return []math.Mat[float64]{g.Hadamard(x[0].Value).Scale(2), {}}
+			}, x[0], x[1])
+			return sq.Add(x[1])
+		}, []math.Mat[float64]{a, c}},
+	} {
+		if err := autodiff.CheckGrad(tt.f, tt.xs, 1e-6, 1e-6); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+	}
+
+	// A wrong gradient is reported.
+	wrong := func(t *autodiff.Tape[float64], x []*autodiff.Var[float64]) *autodiff.Var[float64] {
+		return x[0].Apply(func(v float64) float64 { return v * v }, func(x, y float64) float64 { return x })
+	}
+	if err := autodiff.CheckGrad(wrong, []math.Mat[float64]{a}, 1e-6, 1e-6); err == nil {
+		t.Errorf("want an error for a wrong gradient")
+	}
+}
+
+func TestBackward(t *testing.T) {
+	a := math.NewRandMat[float32](5, 7, math.WithSeed(1))
+	b := math.NewRandMat[float32](7, 3, math.WithSeed(2))
+	unused := math.NewRandMat[float32](2, 2, math.WithSeed(1))
+
+	for _, mul := range []math.MulFunc[float32]{nil, gpu.Mul[float32]} {
+		tape := &autodiff.Tape[float32]{Mul: mul}
+		x, y, u := tape.Var(a), tape.Var(b), tape.Var(unused)
+		out := x.Mul(y)
+		out.Backward()
+
+		// d sum(AB)/dA = 1 B^T and d sum(AB)/dB = A^T 1.
+		ones := math.Ones[float32](5, 3)
+		wantA := ones.MulNaive(b.View().T().Clone())
+		wantB := a.View().T().Clone().MulNaive(ones)
+		if r := math.Compare(x.Grad, wantA, math.CompareOptions{Rel: 1e-5}); !r.Equal() {
+			t.Fatalf("dA: %v", r)
+		}
+		if r := math.Compare(y.Grad, wantB, math.CompareOptions{Rel: 1e-5}); !r.Equal() {
+			t.Fatalf("dB: %v", r)
+		}
+		if !u.Grad.Eq(math.Zeros[float32](2, 2)) || !x.Leaf() || out.Leaf() {
+			t.Fatalf("unused leaf: got %v", u.Grad)
+		}
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("want a panic for the backward pass of a tape without gradients")
+			}
+		}()
+		tape := &autodiff.Tape[float32]{NoGrad: true}
+		tape.Var(a).Mul(tape.Var(b)).Backward()
+	}()
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("want a panic for variables of different tapes")
+		}
+	}()
+	var t1, t2 autodiff.Tape[float32]
+	t1.Var(a).Mul(t2.Var(b))
+}
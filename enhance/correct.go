@@ -0,0 +1,62 @@
+// Copyright 2023 Changkun Ou <changkun.de>. All rights reserved.
+// Use of this source code is governed by a MIT license that
+// can be found in the LICENSE file.
+
+package enhance
+
+import (
+	"errors"
+
+	"changkun.de/x/gogpu/math"
+)
+
+// ColorMatrix is an affine color transform. The first three columns
+// are the linear part, and the last column is the offset.
+type ColorMatrix [3][4]float32
+
+// FitColorMatrix fits the color transform that maps the colors of src
+// to the colors of dst in the least-squares sense, for instance from the
+// captured to the reference patches of a color chart. At least four
+// pairs of colors are required.
+func FitColorMatrix(src, dst []Color) (ColorMatrix, error) {
+	if len(src) != len(dst) {
+		return ColorMatrix{}, errors.New("enhance: mismatched number of colors")
+	}
+	if len(src) < 4 {
+		return ColorMatrix{}, errors.New("enhance: not enough colors to fit a color matrix")
+	}
+
+	a := math.Mat[float32]{Row: len(src), Col: 4, Data: make([]float32, len(src)*4)}
+	b := math.Mat[float32]{Row: len(dst), Col: 3, Data: make([]float32, len(dst)*3)}
+	for i := range src {
+		a.Set(i, 0, src[i].R)
+		a.Set(i, 1, src[i].G)
+		a.Set(i, 2, src[i].B)
+		a.Set(i, 3, 1)
+		b.Set(i, 0, dst[i].R)
+		b.Set(i, 1, dst[i].G)
+		b.Set(i, 2, dst[i].B)
+	}
+
+	x, _, err := math.LeastSquares(a, b)
+	if err != nil {
+		return ColorMatrix{}, err
+	}
+
+	var m ColorMatrix
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 4; j++ {
+			m[i][j] = x.Get(j, i)
+		}
+	}
+	return m, nil
+}
+
+// Apply applies the color transform to a given color.
+func (m ColorMatrix) Apply(c Color) Color {
+	v := [3]float32{}
+	for i := range v {
+		v[i] = m[i][0]*c.R + m[i][1]*c.G + m[i][2]*c.B + m[i][3]
+	}
+	return Color{R: v[0], G: v[1], B: v[2]}
+}
@@ -0,0 +1,15 @@
+// Copyright 2023 Changkun Ou <changkun.de>. All rights reserved.
+// Use of this source code is governed by a MIT license that
+// can be found in the LICENSE file.
+
+//go:build !darwin
+
+package enhance
+
+import "image"
+
+// ImageGPU is a GPU version of Image. Without a GPU backend on this
+// platform, it falls back to Image.
+func ImageGPU(m *image.RGBA, params Params) *image.RGBA {
+	return Image(m, params)
+}
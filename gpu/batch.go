@@ -0,0 +1,69 @@
+// Copyright 2023 Changkun Ou <changkun.de>. All rights reserved.
+// Use of this source code is governed by a MIT license that
+// can be found in the LICENSE file.
+
+package gpu
+
+import "changkun.de/x/gogpu/math"
+
+// MulBatched is a GPU version of math.MulBatched. If all matrices of a
+// batch have the same shape, the whole batch is multiplied in a single
+// dispatch, which amortizes the fixed cost of a GPU call across many
+// small matrices. Otherwise the batch runs on the CPU.
+func MulBatched[T math.Type](as, bs []math.Mat[T]) []math.Mat[T] {
+	n := math.BatchLen(len(as), len(bs))
+	if n == 0 || !accelerated(as...) || !accelerated(bs...) || !sameShape(as) || !sameShape(bs) {
+		return math.MulBatched(as, bs)
+	}
+
+	r := TensorMatMul(stack(as), stack(bs))
+	// The results share the elements of r, and their capacity ends at
+	// their last element, hence appending to one does not overwrite the
+	// next.
+	rs := make([]math.Mat[T], n)
+	for i := range rs {
+		lo, hi := i*r.Strides[0], (i+1)*r.Strides[0]
+		rs[i] = math.Mat[T]{
+			Row:  r.Shape[1],
+			Col:  r.Shape[2],
+			Data: r.Data[lo:hi:hi],
+		}
+	}
+	return rs
+}
+
+// TensorMatMul is a GPU version of math.Tensor[T].MatMul.
+func TensorMatMul[T math.Type](t1, t2 math.Tensor[T]) math.Tensor[T] {
+	if t1.Dim() != 3 || t2.Dim() != 3 || t1.Shape[2] != t2.Shape[1] {
+		panic("math: mismatched matrix dimension")
+	}
+	if !acceleratedTensor(t1, t2) {
+		return t1.MatMul(t2)
+	}
+	return mulBatched(t1, t2)
+}
+
+// sameShape reports whether all matrices have the same shape.
+func sameShape[T math.Type](ms []math.Mat[T]) bool {
+	for _, m := range ms {
+		if m.Row != ms[0].Row || m.Col != ms[0].Col {
+			return false
+		}
+	}
+	return true
+}
+
+// stack copies matrices of the same shape into a dense 3-D tensor.
+func stack[T math.Type](ms []math.Mat[T]) math.Tensor[T] {
+	row, col := ms[0].Row, ms[0].Col
+	t := math.NewTensor[T](len(ms), row, col)
+	for i, m := range ms {
+		if m.Row != row || m.Col != col {
+			panic("math: mismatched matrix dimension")
+		}
+		for j := 0; j < row; j++ {
+			copy(t.Data[t.Index(i, j, 0):], m.Data[m.Index(j, 0):m.Index(j, col)])
+		}
+	}
+	return t
+}
@@ -0,0 +1,468 @@
+// Copyright 2023 Changkun Ou <changkun.de>. All rights reserved.
+// Use of this source code is governed by a MIT license that
+// can be found in the LICENSE file.
+
+package gpu
+
+import (
+	_ "embed"
+	"fmt"
+	"sync"
+	"unsafe"
+
+	"changkun.de/x/gogpu/gpu/mtl"
+	"changkun.de/x/gogpu/math"
+)
+
+func mul[T math.Type](m1, m2 math.Mat[T]) math.Mat[T] {
+	return mulKernel[T, T](lookup[T]("mul"), m1, m2)
+}
+
+// deviceMemory returns the size of the largest buffer and the memory
+// that the device can use without affecting its performance, in bytes.
+func deviceMemory() (maxBuffer, workingSet int) {
+	return int(device.MaxBufferLength), int(device.RecommendedMaxWorkingSetSize)
+}
+
+// metalBackend multiplies the tiles of MulBudget by the mulAdd kernel.
+// Its matrices are backed by buffers in the shared storage, which are
+// looked up by the address of their first element.
+type metalBackend[T math.Type] struct {
+	mu   sync.Mutex
+	bufs map[*T]mtl.Buffer
+}
+
+func newDeviceBackend[T math.Type]() Backend[T] {
+	return &metalBackend[T]{bufs: map[*T]mtl.Buffer{}}
+}
+
+func (be *metalBackend[T]) Alloc(row, col int) math.Mat[T] {
+	m := math.Mat[T]{Row: row, Col: col}
+	if row*col == 0 {
+		return m
+	}
+	buf := device.MakeBuffer(nil, uintptr(math.TypeSize[T]()*row*col), mtl.ResourceStorageModeShared)
+	m.Data = unsafe.Slice((*T)(buf.Content()), row*col)
+	for i := range m.Data {
+		m.Data[i] = 0
+	}
+
+	be.mu.Lock()
+	be.bufs[&m.Data[0]] = buf
+	be.mu.Unlock()
+	return m
+}
+
+func (be *metalBackend[T]) Free(m math.Mat[T]) {
+	if len(m.Data) == 0 {
+		return
+	}
+	be.mu.Lock()
+	buf := be.bufs[&m.Data[0]]
+	delete(be.bufs, &m.Data[0])
+	be.mu.Unlock()
+	buf.Release()
+}
+
+func (be *metalBackend[T]) buffer(m math.Mat[T]) mtl.Buffer {
+	be.mu.Lock()
+	defer be.mu.Unlock()
+	return be.bufs[&m.Data[0]]
+}
+
+func (be *metalBackend[T]) MulAdd(acc, a, b math.Mat[T]) {
+	if len(acc.Data) == 0 || a.Col == 0 {
+		return
+	}
+	dp := device.MakeBuffer(unsafe.Pointer(&params[T]{
+		ColA:    int32(a.Col),
+		ColB:    int32(b.Col),
+		StrideA: int32(a.Col),
+		StrideB: int32(b.Col),
+	}), unsafe.Sizeof(params[T]{}), mtl.ResourceStorageModeShared)
+	defer dp.Release()
+
+	dispatch(lookup[T]("mulAdd"), acc.Row*acc.Col, be.buffer(a), be.buffer(b), be.buffer(acc), dp)
+}
+
+// mulSemiring multiplies two matrices over a built-in semiring.
+func mulSemiring[T math.Type](m1, m2 math.Mat[T], sr math.Semiring[T]) math.Mat[T] {
+	a := upload(m1)
+	defer a.Release()
+	b := upload(m2)
+	defer b.Release()
+	out := device.MakeBuffer(nil, uintptr(math.TypeSize[T]()*m1.Row*m2.Col), mtl.ResourceStorageModeShared)
+	defer out.Release()
+	dp := device.MakeBuffer(unsafe.Pointer(&semiringParams{
+		ColA:    int32(m1.Col),
+		ColB:    int32(m2.Col),
+		StrideA: int32(m1.RowStride()),
+		StrideB: int32(m2.RowStride()),
+		Op:      int32(sr.Op),
+	}), unsafe.Sizeof(semiringParams{}), mtl.ResourceStorageModeShared)
+	defer dp.Release()
+	zero := device.MakeBuffer(unsafe.Pointer(&sr.Zero), uintptr(math.TypeSize[T]()), mtl.ResourceStorageModeShared)
+	defer zero.Release()
+
+	dispatch(lookup[T]("mulSemiring"), m1.Row*m2.Col, a, b, out, dp, zero)
+
+	r := math.Mat[T]{Row: m1.Row, Col: m2.Col, Data: make([]T, m1.Row*m2.Col)}
+	copy(r.Data, unsafe.Slice((*T)(out.Content()), len(r.Data)))
+	return r
+}
+
+// mulMixed multiplies half-precision matrices with float32 accumulation.
+func mulMixed[T math.Half](m1, m2 math.Mat[T]) math.Mat[float32] {
+	return mulKernel[T, float32](lookup[T]("mulMixed"), m1, m2)
+}
+
+// mulQuantized multiplies quantized matrices with int32 accumulation
+// and requantizes the result.
+func mulQuantized[T math.Q8](q1, q2 math.QMat[T], scale float32, zero int32) math.QMat[T] {
+	m1, m2 := q1.Mat, q2.Mat
+	a := upload(m1)
+	defer a.Release()
+	b := upload(m2)
+	defer b.Release()
+	out := device.MakeBuffer(nil, uintptr(m1.Row*m2.Col), mtl.ResourceStorageModeShared)
+	defer out.Release()
+
+	p := quantParams{
+		ColA:    int32(m1.Col),
+		ColB:    int32(m2.Col),
+		StrideA: int32(m1.RowStride()),
+		StrideB: int32(m2.RowStride()),
+		ZeroA:   q1.ZeroPoint,
+		ZeroB:   q2.ZeroPoint,
+		ZeroOut: zero,
+		Mult:    q1.Scale * q2.Scale / scale,
+	}
+	p.Lo, p.Hi = math.QuantRange[T]()
+	dp := device.MakeBuffer(unsafe.Pointer(&p), unsafe.Sizeof(p), mtl.ResourceStorageModeShared)
+	defer dp.Release()
+
+	dispatch(lookup[T]("mulQuantized"), m1.Row*m2.Col, a, b, out, dp)
+
+	r := math.QMat[T]{
+		Mat:       math.Mat[T]{Row: m1.Row, Col: m2.Col, Data: make([]T, m1.Row*m2.Col)},
+		Scale:     scale,
+		ZeroPoint: zero,
+	}
+	copy(r.Mat.Data, unsafe.Slice((*T)(out.Content()), len(r.Mat.Data)))
+	return r
+}
+
+// mulKernel runs a multiplication kernel that reads elements of type T
+// and writes elements of type R.
+func mulKernel[T, R math.Elem](k kernel, m1, m2 math.Mat[T]) math.Mat[R] {
+	// Allocate GPU buffers. Strided matrices are uploaded as is,
+	// and the kernel skips the gaps between their rows.
+	a := upload(m1)
+	defer a.Release()
+	b := upload(m2)
+	defer b.Release()
+	out := device.MakeBuffer(nil, uintptr(math.TypeSize[R]()*m1.Row*m2.Col), mtl.ResourceStorageModeShared)
+	defer out.Release()
+	dp := device.MakeBuffer(unsafe.Pointer(&params[T]{
+		ColA:    int32(m1.Col),
+		ColB:    int32(m2.Col),
+		StrideA: int32(m1.RowStride()),
+		StrideB: int32(m2.RowStride()),
+	}), unsafe.Sizeof(params[T]{}), mtl.ResourceStorageModeShared)
+	defer dp.Release()
+
+	dispatch(k, m1.Row*m2.Col, a, b, out, dp)
+
+	// Copy data from GPU buffer to CPU buffer
+	data := make([]R, m1.Row*m2.Col)
+	copy(data, unsafe.Slice((*R)(out.Content()), m1.Row*m2.Col))
+	return math.Mat[R]{
+		Row:  m1.Row,
+		Col:  m2.Col,
+		Data: data,
+	}
+}
+
+// binary applies an element-wise operation on two tensors that are
+// broadcast against each other.
+func binary[T math.Type](op int, t1, t2 math.Tensor[T]) math.Tensor[T] {
+	shape := math.BroadcastShapes(t1.Shape, t2.Shape)
+	t1, t2 = t1.BroadcastTo(shape...), t2.BroadcastTo(shape...)
+	n := t1.Size()
+
+	// A broadcast dimension has a zero stride, which makes the
+	// kernel reading its first element repeatedly.
+	p := binaryParams{Op: int32(op), NDim: int32(len(shape))}
+	for i := range shape {
+		p.Shape[i] = int32(shape[i])
+		p.StrideA[i] = int32(t1.Strides[i])
+		p.StrideB[i] = int32(t2.Strides[i])
+	}
+
+	a := uploadTensor(t1)
+	defer a.Release()
+	b := uploadTensor(t2)
+	defer b.Release()
+	out := device.MakeBuffer(nil, uintptr(math.TypeSize[T]()*n), mtl.ResourceStorageModeShared)
+	defer out.Release()
+	dp := device.MakeBuffer(unsafe.Pointer(&p), unsafe.Sizeof(p), mtl.ResourceStorageModeShared)
+	defer dp.Release()
+
+	dispatch(lookup[T]("binary"), n, a, b, out, dp)
+
+	r := math.NewTensor[T](shape...)
+	copy(r.Data, unsafe.Slice((*T)(out.Content()), n))
+	return r
+}
+
+// mulBatched multiplies the matrices of two 3-D tensors in one dispatch.
+func mulBatched[T math.Type](t1, t2 math.Tensor[T]) math.Tensor[T] {
+	n := math.BatchLen(t1.Shape[0], t2.Shape[0])
+	t1 = t1.BroadcastTo(n, t1.Shape[1], t1.Shape[2])
+	t2 = t2.BroadcastTo(n, t2.Shape[1], t2.Shape[2])
+	r := math.NewTensor[T](n, t1.Shape[1], t2.Shape[2])
+
+	p := batchParams{
+		M: int32(t1.Shape[1]),
+		K: int32(t1.Shape[2]),
+		N: int32(t2.Shape[2]),
+	}
+	for i := 0; i < 3; i++ {
+		p.StrideA[i] = int32(t1.Strides[i])
+		p.StrideB[i] = int32(t2.Strides[i])
+	}
+
+	a := uploadTensor(t1)
+	defer a.Release()
+	b := uploadTensor(t2)
+	defer b.Release()
+	out := device.MakeBuffer(nil, uintptr(math.TypeSize[T]()*len(r.Data)), mtl.ResourceStorageModeShared)
+	defer out.Release()
+	dp := device.MakeBuffer(unsafe.Pointer(&p), unsafe.Sizeof(p), mtl.ResourceStorageModeShared)
+	defer dp.Release()
+
+	dispatch(lookup[T]("mulBatched"), len(r.Data), a, b, out, dp)
+
+	copy(r.Data, unsafe.Slice((*T)(out.Content()), len(r.Data)))
+	return r
+}
+
+// reduce reduces a matrix along the given axis, and returns the reduced
+// values as well as the positions where the values were found. The
+// opMean reduction divides the sums by div.
+func reduce[T math.Type](op int, m math.Mat[T], axis, div int) (math.Mat[T], []int) {
+	var r math.Mat[T]
+	p := reduceParams{Op: int32(op), Div: int32(div)}
+	switch axis {
+	case 0:
+		r = math.Mat[T]{Row: 1, Col: m.Col}
+		p.N, p.OuterStride, p.InnerStride = int32(m.Row), 1, int32(m.RowStride())
+	case 1:
+		r = math.Mat[T]{Row: m.Row, Col: 1}
+		p.N, p.OuterStride, p.InnerStride = int32(m.Col), int32(m.RowStride()), 1
+	default:
+		panic("math: invalid axis")
+	}
+	n := r.Row * r.Col
+
+	in := upload(m)
+	defer in.Release()
+	out := device.MakeBuffer(nil, uintptr(math.TypeSize[T]()*n), mtl.ResourceStorageModeShared)
+	defer out.Release()
+	arg := device.MakeBuffer(nil, uintptr(4*n), mtl.ResourceStorageModeShared)
+	defer arg.Release()
+	dp := device.MakeBuffer(unsafe.Pointer(&p), unsafe.Sizeof(p), mtl.ResourceStorageModeShared)
+	defer dp.Release()
+
+	dispatch(lookup[T]("reduce"), n, in, out, arg, dp)
+
+	r.Data = make([]T, n)
+	copy(r.Data, unsafe.Slice((*T)(out.Content()), n))
+	idx := make([]int, n)
+	for i, v := range unsafe.Slice((*uint32)(arg.Content()), n) {
+		idx[i] = int(v)
+	}
+	return r, idx
+}
+
+// upload copies the elements covered by m to a new GPU buffer.
+func upload[T math.Elem](m math.Mat[T]) mtl.Buffer {
+	return device.MakeBuffer(unsafe.Pointer(&m.Data[0]), uintptr(math.TypeSize[T]()*span(m)), mtl.ResourceStorageModeShared)
+}
+
+// uploadTensor copies the elements covered by t to a new GPU buffer.
+func uploadTensor[T math.Type](t math.Tensor[T]) mtl.Buffer {
+	return device.MakeBuffer(unsafe.Pointer(&t.Data[0]), uintptr(math.TypeSize[T]()*spanTensor(t)), mtl.ResourceStorageModeShared)
+}
+
+// dispatch runs a kernel with n threads and the given buffers bound
+// in order, then waits for its completion.
+func dispatch(k kernel, n int, bufs ...mtl.Buffer) {
+	// Create command buffer
+	cb := cq.MakeCommandBuffer()
+	defer cb.Release()
+
+	// Encode, dispatch threads, then commit and wait for completion
+	ce := cb.MakeComputeCommandEncoder()
+	ce.SetComputePipelineState(k.cps)
+	for i, b := range bufs {
+		ce.SetBuffer(b, 0, i)
+	}
+	ce.DispatchThreads(
+		mtl.Size{Width: n, Height: 1, Depth: 1},
+		mtl.Size{Width: 1, Height: 1, Depth: 1})
+	ce.EndEncoding()
+	cb.Commit()
+	cb.WaitUntilCompleted()
+}
+
+var (
+	//go:embed mul.metal
+	mathMetal string
+	//go:embed ops.metal
+	opsMetal string
+
+	once   sync.Once
+	fn     gpuFunc
+	device mtl.Device
+	cq     mtl.CommandQueue
+)
+
+type kernel struct {
+	fn  mtl.Function
+	cps mtl.ComputePipelineState
+}
+
+type gpuFunc struct {
+	lib     mtl.Library
+	kernels map[string]kernel
+}
+
+// kernelNames lists the kernels in the Metal library. Every kernel is
+// instantiated for each supported element type, see metalType.
+var kernelNames = []string{"mul", "mulAdd", "mulBatched", "mulSemiring", "binary", "reduce"}
+
+// metalTypes lists the Metal names of the supported element types.
+var metalTypes = []string{"float", "int", "uint", "char", "uchar"}
+
+// mixedTypes lists the Metal names of the half-precision types, which
+// are only read by the mulMixed kernel.
+var mixedTypes = []string{"half", "bfloat16"}
+
+// quantTypes lists the Metal names of the quantized element types of
+// the mulQuantized kernel.
+var quantTypes = []string{"char", "uchar"}
+
+// metalType returns the Metal name of the element type T.
+func metalType[T math.Elem]() string {
+	var v T
+	switch any(v).(type) {
+	case float32:
+		return "float"
+	case int32:
+		return "int"
+	case uint32:
+		return "uint"
+	case int8:
+		return "char"
+	case uint8:
+		return "uchar"
+	case math.Float16:
+		return "half"
+	case math.BFloat16:
+		return "bfloat16"
+	}
+	panic(fmt.Errorf("%w: %T", ErrUnsupportedType, v))
+}
+
+// lookup returns the instantiation of the named kernel for type T.
+func lookup[T math.Elem](name string) kernel {
+	return fn.kernels[name+"_"+metalType[T]()]
+}
+
+func init() {
+	defer handle(func(err error) {
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	once.Do(func() {
+		device = try(mtl.CreateSystemDefaultDevice())
+		cq = device.MakeCommandQueue()
+
+		lib := try(device.MakeLibrary(mathMetal+"\n"+opsMetal, mtl.CompileOptions{
+			LanguageVersion: mtl.LanguageVersion2_4,
+		}))
+
+		fn = gpuFunc{lib: lib, kernels: map[string]kernel{}}
+		for _, name := range kernelNames {
+			for _, typ := range metalTypes {
+				fn.kernels[name+"_"+typ] = makeKernel(lib, name+"_"+typ)
+			}
+		}
+		for _, typ := range mixedTypes {
+			fn.kernels["mulMixed_"+typ] = makeKernel(lib, "mulMixed_"+typ)
+		}
+		for _, typ := range quantTypes {
+			fn.kernels["mulQuantized_"+typ] = makeKernel(lib, "mulQuantized_"+typ)
+		}
+	})
+}
+
+func makeKernel(lib mtl.Library, name string) kernel {
+	f := try(lib.MakeFunction(name))
+	return kernel{fn: f, cps: try(device.MakeComputePipelineState(f))}
+}
+
+type params[T math.Elem] struct {
+	ColA    int32
+	ColB    int32
+	StrideA int32
+	StrideB int32
+}
+
+type semiringParams struct {
+	ColA    int32
+	ColB    int32
+	StrideA int32
+	StrideB int32
+	Op      int32
+}
+
+type quantParams struct {
+	ColA    int32
+	ColB    int32
+	StrideA int32
+	StrideB int32
+	ZeroA   int32
+	ZeroB   int32
+	ZeroOut int32
+	Lo      int32
+	Hi      int32
+	Mult    float32
+}
+
+type batchParams struct {
+	M       int32
+	K       int32
+	N       int32
+	StrideA [3]int32
+	StrideB [3]int32
+}
+
+type binaryParams struct {
+	Op      int32
+	NDim    int32
+	Shape   [maxDim]int32
+	StrideA [maxDim]int32
+	StrideB [maxDim]int32
+}
+
+type reduceParams struct {
+	Op          int32
+	N           int32
+	OuterStride int32
+	InnerStride int32
+	Div         int32
+}
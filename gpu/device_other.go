@@ -0,0 +1,54 @@
+// Copyright 2023 Changkun Ou <changkun.de>. All rights reserved.
+// Use of this source code is governed by a MIT license that
+// can be found in the LICENSE file.
+
+//go:build !darwin
+
+package gpu
+
+import "changkun.de/x/gogpu/math"
+
+// There is no GPU backend other than Metal yet, hence all operations
+// run on the CPU and the following functions are never called.
+
+type nodevice struct{}
+
+func (nodevice) Available() bool { return false }
+
+var device nodevice
+
+func mul[T math.Type](m1, m2 math.Mat[T]) math.Mat[T] {
+	panic("gpu: no device available")
+}
+
+func mulSemiring[T math.Type](m1, m2 math.Mat[T], sr math.Semiring[T]) math.Mat[T] {
+	panic("gpu: no device available")
+}
+
+func mulMixed[T math.Half](m1, m2 math.Mat[T]) math.Mat[float32] {
+	panic("gpu: no device available")
+}
+
+func mulQuantized[T math.Q8](m1, m2 math.QMat[T], scale float32, zero int32) math.QMat[T] {
+	panic("gpu: no device available")
+}
+
+func mulBatched[T math.Type](t1, t2 math.Tensor[T]) math.Tensor[T] {
+	panic("gpu: no device available")
+}
+
+func binary[T math.Type](op int, t1, t2 math.Tensor[T]) math.Tensor[T] {
+	panic("gpu: no device available")
+}
+
+func reduce[T math.Type](op int, m math.Mat[T], axis, div int) (math.Mat[T], []int) {
+	panic("gpu: no device available")
+}
+
+func newDeviceBackend[T math.Type]() Backend[T] {
+	panic("gpu: no device available")
+}
+
+func deviceMemory() (maxBuffer, workingSet int) {
+	panic("gpu: no device available")
+}
@@ -5,12 +5,10 @@
 package gpu
 
 import (
-	_ "embed"
 	"errors"
-	"sync"
-	"unsafe"
+	"fmt"
+	"sync/atomic"
 
-	"changkun.de/x/gogpu/gpu/mtl"
 	"changkun.de/x/gogpu/math"
 )
 
@@ -22,96 +20,143 @@ type Device interface {
 // Driver returns an avaliable GPU device.
 func Driver() Device { return device }
 
+// ErrUnsupportedType indicates that the GPU device cannot process
+// elements of the given type.
+var ErrUnsupportedType = errors.New("gpu: unsupported type on this backend")
+
 // Mul is a GPU version of math.Mat[T].Mul method and it multiplies
 // two matrices m1 and m2 and returns the result.
+//
+// If no GPU device is available, the multiplication runs on the CPU.
+// It panics with ErrUnsupportedType if the device cannot process
+// elements of type T. Matrices that exceed the device memory, or the
+// budget of SetMemoryBudget, are multiplied in tiles, see MulBudget.
 func Mul[T math.Type](m1, m2 math.Mat[T]) math.Mat[T] {
 	if m1.Col != m2.Row {
 		panic("math: mismatched matrix dimension")
 	}
+	if device.Available() && !Supports[T]() {
+		var v T
+		panic(fmt.Errorf("%w: %T", ErrUnsupportedType, v))
+	}
+	if !accelerated(m1, m2) {
+		return m1.Mul(m2)
+	}
 
-	// Allocate GPU buffers
-	a := device.MakeBuffer(unsafe.Pointer(&m1.Data[0]), uintptr(math.TypeSize[T]()*len(m1.Data)), mtl.ResourceStorageModeShared)
-	defer a.Release()
-	b := device.MakeBuffer(unsafe.Pointer(&m2.Data[0]), uintptr(math.TypeSize[T]()*len(m2.Data)), mtl.ResourceStorageModeShared)
-	defer b.Release()
-	out := device.MakeBuffer(nil, uintptr(math.TypeSize[T]()*m1.Row*m2.Col), mtl.ResourceStorageModeShared)
-	defer out.Release()
-	dp := device.MakeBuffer(unsafe.Pointer(&params[T]{
-		ColA: int32(m1.Col),
-		ColB: int32(m2.Col),
-	}), unsafe.Sizeof(params[T]{}), mtl.ResourceStorageModeShared)
-	defer dp.Release()
-
-	// Create command buffer
-	cb := cq.MakeCommandBuffer()
-	defer cb.Release()
-
-	// Encode, dispatch threads, then commit and wait for completion
-	ce := cb.MakeComputeCommandEncoder()
-	ce.SetComputePipelineState(fn.funcMul.cps)
-	ce.SetBuffer(a, 0, 0)
-	ce.SetBuffer(b, 0, 1)
-	ce.SetBuffer(out, 0, 2)
-	ce.SetBuffer(dp, 0, 3)
-	ce.DispatchThreads(
-		mtl.Size{Width: m1.Row * m2.Col, Height: 1, Depth: 1},
-		mtl.Size{Width: 1, Height: 1, Depth: 1})
-	ce.EndEncoding()
-	cb.Commit()
-	cb.WaitUntilCompleted()
-
-	// Copy data from GPU buffer to CPU buffer
-	data := make([]T, m1.Row*m2.Col)
-	copy(data, unsafe.Slice((*T)(out.Content()), m1.Row*m2.Col))
-	return math.Mat[T]{
-		Row:  m1.Row,
-		Col:  m2.Col,
-		Data: data,
+	size := math.TypeSize[T]()
+	a, b, c := span(m1)*size, span(m2)*size, m1.Row*m2.Col*size
+	maxBuffer, workingSet := deviceMemory()
+	limit, budget := workingSet, workingSet/2
+	if n := int(memoryBudget.Load()); n > 0 {
+		limit, budget = n, n
+	}
+	if a > maxBuffer || b > maxBuffer || c > maxBuffer || a+b+c > limit {
+		if budget > maxBuffer {
+			budget = maxBuffer
+		}
+		r := math.Mat[T]{Row: m1.Row, Col: m2.Col, Data: make([]T, m1.Row*m2.Col)}
+		MulBudget(r, m1, m2, budget, newDeviceBackend[T]())
+		return r
 	}
+	return mul(m1, m2)
 }
 
-var (
-	//go:embed mul.metal
-	mathMetal string
-
-	once   sync.Once
-	fn     gpuFunc
-	device mtl.Device
-	cq     mtl.CommandQueue
-)
+// memoryBudget is the budget of SetMemoryBudget, or zero.
+var memoryBudget atomic.Int64
+
+// SetMemoryBudget limits the device memory that Mul allocates to the
+// given number of bytes. Operands and results that exceed the budget
+// are multiplied in tiles of the budget. A zero budget restores the
+// default, which tiles matrices that exceed the recommended working
+// set of the device in half of it. It panics for a negative budget.
+func SetMemoryBudget(bytes int) {
+	if bytes < 0 {
+		panic("gpu: negative memory budget")
+	}
+	memoryBudget.Store(int64(bytes))
+}
 
-type gpuFunc struct {
-	lib     mtl.Library
-	funcMul struct {
-		fn  mtl.Function
-		cps mtl.ComputePipelineState
+// MulSemiring is a GPU version of math.MulSemiring and it multiplies
+// two matrices over the semiring sr.
+//
+// Only the built-in semirings run on the GPU, whereas a semiring with
+// custom Add and Mul functions runs on the CPU.
+func MulSemiring[T math.Type](m1, m2 math.Mat[T], sr math.Semiring[T]) math.Mat[T] {
+	if m1.Col != m2.Row {
+		panic("math: mismatched matrix dimension")
+	}
+	if sr.Op == math.CustomSemiring || !accelerated(m1, m2) {
+		return math.MulSemiring(m1, m2, sr)
 	}
+	return mulSemiring(m1, m2, sr)
 }
 
-func init() {
-	defer handle(func(err error) {
-		if err != nil {
-			panic(err)
-		}
-	})
+// MulMixed is a GPU version of math.MulMixed and it multiplies two
+// half-precision matrices with float32 accumulation.
+//
+// If no GPU device is available, the multiplication runs on the CPU.
+func MulMixed[T math.Half](m1, m2 math.Mat[T]) math.Mat[float32] {
+	if m1.Col != m2.Row {
+		panic("math: mismatched matrix dimension")
+	}
+	if !device.Available() || span(m1) == 0 || span(m2) == 0 {
+		return math.MulMixed(m1, m2)
+	}
+	return mulMixed(m1, m2)
+}
 
-	once.Do(func() {
-		device = try(mtl.CreateSystemDefaultDevice())
-		cq = device.MakeCommandQueue()
+// MulQuantized is a GPU version of math.MulQuantized and it multiplies
+// two quantized matrices with int32 accumulation, then requantizes the
+// result with the given scale and zero point.
+//
+// If no GPU device is available, the multiplication runs on the CPU.
+func MulQuantized[T math.Q8](m1, m2 math.QMat[T], scale float32, zero int32) math.QMat[T] {
+	if m1.Mat.Col != m2.Mat.Row {
+		panic("math: mismatched matrix dimension")
+	}
+	if !device.Available() || span(m1.Mat) == 0 || span(m2.Mat) == 0 {
+		return math.MulQuantized(m1, m2, scale, zero)
+	}
+	return mulQuantized(m1, m2, scale, zero)
+}
 
-		lib := try(device.MakeLibrary(mathMetal, mtl.CompileOptions{
-			LanguageVersion: mtl.LanguageVersion2_4,
-		}))
+// Supports reports whether the GPU device can process elements of
+// type T. The Metal kernels support float32, int32, uint32, int8 and
+// uint8, whereas Metal has no float64, int64 and complex types.
+func Supports[T math.Type]() bool {
+	if !device.Available() {
+		return false
+	}
+	var v T
+	switch any(v).(type) {
+	case float32, int32, uint32, int8, uint8:
+		return true
+	}
+	return false
+}
 
-		fn = gpuFunc{lib: lib}
-		fn.funcMul.fn = try(lib.MakeFunction("mul"))
-		fn.funcMul.cps = try(device.MakeComputePipelineState(fn.funcMul.fn))
-	})
+// accelerated reports whether an operation on the given matrices
+// can be executed by the GPU kernels. It returns false if no GPU device
+// is available or the device cannot process elements of type T, hence
+// the operation falls back to the CPU.
+func accelerated[T math.Type](ms ...math.Mat[T]) bool {
+	if !Supports[T]() {
+		return false
+	}
+	for _, m := range ms {
+		if span(m) == 0 {
+			return false
+		}
+	}
+	return true
 }
 
-type params[T math.Type] struct {
-	ColA int32
-	ColB int32
+// span returns the number of elements in Data that are covered by m.
+func span[T math.Elem](m math.Mat[T]) int {
+	if m.Row == 0 || m.Col == 0 {
+		return 0
+	}
+	return (m.Row-1)*m.RowStride() + m.Col
 }
 
 func try[T any](v T, err error) T {
@@ -17,6 +17,8 @@ struct Device {
 	bool         Removable;
 	uint64_t     RegistryID;
 	const char * Name;
+	uint64_t     MaxBufferLength;
+	uint64_t     RecommendedMaxWorkingSetSize;
 };
 
 struct Size {
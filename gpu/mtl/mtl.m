@@ -24,6 +24,8 @@ struct Device CreateSystemDefaultDevice() {
 	d.Removable = device.removable;
 	d.RegistryID = device.registryID;
 	d.Name = device.name.UTF8String;
+	d.MaxBufferLength = device.maxBufferLength;
+	d.RecommendedMaxWorkingSetSize = device.recommendedMaxWorkingSetSize;
 	return d;
 }
 
@@ -36,6 +36,15 @@ type Device struct {
 
 	// Name is the name of the device.
 	Name string
+
+	// MaxBufferLength is the largest buffer, in bytes, that the device
+	// can allocate.
+	MaxBufferLength uint64
+
+	// RecommendedMaxWorkingSetSize is an approximation of how much
+	// memory, in bytes, the device can use without affecting its
+	// performance.
+	RecommendedMaxWorkingSetSize uint64
 }
 
 // CreateSystemDefaultDevice returns the preferred system default Metal device.
@@ -53,6 +62,9 @@ func CreateSystemDefaultDevice() (Device, error) {
 		Removable:  bool(d.Removable),
 		RegistryID: uint64(d.RegistryID),
 		Name:       C.GoString(d.Name),
+
+		MaxBufferLength:              uint64(d.MaxBufferLength),
+		RecommendedMaxWorkingSetSize: uint64(d.RecommendedMaxWorkingSetSize),
 	}, nil
 }
 
@@ -8,22 +8,241 @@ using namespace metal;
 struct params {
     uint colA;
     uint colB;
+    uint strideA;
+    uint strideB;
 };
 
-kernel void mul(device const float*  inA     [[ buffer(0) ]],
-                device const float*  inB     [[ buffer(1) ]],
-                device       float*  out     [[ buffer(2) ]],
+template <typename T>
+kernel void mul(device const T*      inA     [[ buffer(0) ]],
+                device const T*      inB     [[ buffer(1) ]],
+                device       T*      out     [[ buffer(2) ]],
                 device const params& params  [[ buffer(3) ]],
                 uint                 index   [[thread_position_in_grid]]) {
 
     uint i = index / uint(params.colB);
     uint j = index % uint(params.colB);
 
-    float sum = 0.0;
+    T sum = 0;
     for (uint k = 0; k < params.colA; k++) {
-        float a = inA[i * int(params.colA) + k];
-        float b = inB[k * int(params.colB) + j];
+        T a = inA[i * params.strideA + k];
+        T b = inB[k * params.strideB + j];
         sum += a * b;
     }
     out[index] = sum;
-}
\ No newline at end of file
+}
+
+// mulAdd accumulates the product into out, which allows a product to be
+// computed from the partial products of tiles, see MulBudget.
+template <typename T>
+kernel void mulAdd(device const T*      inA     [[ buffer(0) ]],
+                   device const T*      inB     [[ buffer(1) ]],
+                   device       T*      out     [[ buffer(2) ]],
+                   device const params& params  [[ buffer(3) ]],
+                   uint                 index   [[thread_position_in_grid]]) {
+
+    uint i = index / uint(params.colB);
+    uint j = index % uint(params.colB);
+
+    T sum = 0;
+    for (uint k = 0; k < params.colA; k++) {
+        sum += inA[i * params.strideA + k] * inB[k * params.strideB + j];
+    }
+    out[index] += sum;
+}
+
+struct batchParams {
+    uint m;
+    uint k;
+    uint n;
+    uint strideA[3];
+    uint strideB[3];
+};
+
+template <typename T>
+kernel void mulBatched(device const T*           inA     [[ buffer(0) ]],
+                       device const T*           inB     [[ buffer(1) ]],
+                       device       T*           out     [[ buffer(2) ]],
+                       device const batchParams& params  [[ buffer(3) ]],
+                       uint                      index   [[thread_position_in_grid]]) {
+
+    uint b = index / (params.m * params.n);
+    uint i = index % (params.m * params.n) / params.n;
+    uint j = index % params.n;
+
+    device const T* a = inA + b * params.strideA[0] + i * params.strideA[1];
+    device const T* c = inB + b * params.strideB[0] + j * params.strideB[2];
+
+    T sum = 0;
+    for (uint k = 0; k < params.k; k++) {
+        sum += a[k * params.strideA[2]] * c[k * params.strideB[1]];
+    }
+    out[index] = sum;
+}
+
+struct semiringParams {
+    uint colA;
+    uint colB;
+    uint strideA;
+    uint strideB;
+    uint op;
+};
+
+// mulSemiring multiplies over a built-in semiring, see math.SemiringOp.
+// The zero of the semiring is the only element of the zero buffer.
+template <typename T>
+kernel void mulSemiring(device const T*              inA     [[ buffer(0) ]],
+                        device const T*              inB     [[ buffer(1) ]],
+                        device       T*              out     [[ buffer(2) ]],
+                        device const semiringParams& params  [[ buffer(3) ]],
+                        device const T*              zero    [[ buffer(4) ]],
+                        uint                         index   [[thread_position_in_grid]]) {
+
+    uint i = index / params.colB;
+    uint j = index % params.colB;
+
+    T z = zero[0];
+    T acc = z;
+    for (uint k = 0; k < params.colA; k++) {
+        T a = inA[i * params.strideA + k];
+        T b = inB[k * params.strideB + j];
+        switch (params.op) {
+        case 1: // plus-times
+            acc += a * b;
+            break;
+        case 2: // min-plus
+            if (a != z && b != z) {
+                acc = min(acc, T(a + b));
+            }
+            break;
+        case 3: // max-plus
+            if (a != z && b != z) {
+                acc = max(acc, T(a + b));
+            }
+            break;
+        case 4: // or-and
+            acc = (acc != 0 || (a != 0 && b != 0)) ? T(1) : T(0);
+            break;
+        }
+    }
+    out[index] = acc;
+}
+
+// widen converts a half-precision number to float. A bfloat16 number is
+// stored as the upper half of the bits of a float.
+inline float widen(half v)   { return float(v); }
+inline float widen(ushort v) { return as_type<float>(uint(v) << 16); }
+
+template <typename T>
+kernel void mulMixed(device const T*      inA     [[ buffer(0) ]],
+                     device const T*      inB     [[ buffer(1) ]],
+                     device       float*  out     [[ buffer(2) ]],
+                     device const params& params  [[ buffer(3) ]],
+                     uint                 index   [[thread_position_in_grid]]) {
+
+    uint i = index / uint(params.colB);
+    uint j = index % uint(params.colB);
+
+    float sum = 0;
+    for (uint k = 0; k < params.colA; k++) {
+        float a = widen(inA[i * params.strideA + k]);
+        float b = widen(inB[k * params.strideB + j]);
+        sum += a * b;
+    }
+    out[index] = sum;
+}
+
+template [[host_name("mulMixed_half")]]
+kernel void mulMixed<half>(device const half*   inA     [[ buffer(0) ]],
+                           device const half*   inB     [[ buffer(1) ]],
+                           device       float*  out     [[ buffer(2) ]],
+                           device const params& params  [[ buffer(3) ]],
+                           uint                 index   [[thread_position_in_grid]]);
+template [[host_name("mulMixed_bfloat16")]]
+kernel void mulMixed<ushort>(device const ushort* inA     [[ buffer(0) ]],
+                             device const ushort* inB     [[ buffer(1) ]],
+                             device       float*  out     [[ buffer(2) ]],
+                             device const params& params  [[ buffer(3) ]],
+                             uint                 index   [[thread_position_in_grid]]);
+
+// The kernels are instantiated for every element type that is supported
+// by the device, and named by the kernel and the type, e.g. mul_float.
+#define instantiateMul(T)                                                     \
+template [[host_name("mul_" #T)]]                                             \
+kernel void mul<T>(device const T*      inA     [[ buffer(0) ]],              \
+                   device const T*      inB     [[ buffer(1) ]],              \
+                   device       T*      out     [[ buffer(2) ]],              \
+                   device const params& params  [[ buffer(3) ]],              \
+                   uint                 index   [[thread_position_in_grid]]); \
+template [[host_name("mulAdd_" #T)]]                                          \
+kernel void mulAdd<T>(device const T*      inA     [[ buffer(0) ]],           \
+                      device const T*      inB     [[ buffer(1) ]],           \
+                      device       T*      out     [[ buffer(2) ]],           \
+                      device const params& params  [[ buffer(3) ]],           \
+                      uint                 index   [[thread_position_in_grid]]); \
+template [[host_name("mulBatched_" #T)]]                                      \
+kernel void mulBatched<T>(device const T*           inA     [[ buffer(0) ]],  \
+                          device const T*           inB     [[ buffer(1) ]],  \
+                          device       T*           out     [[ buffer(2) ]],  \
+                          device const batchParams& params  [[ buffer(3) ]],  \
+                          uint                      index   [[thread_position_in_grid]]); \
+template [[host_name("mulSemiring_" #T)]]                                     \
+kernel void mulSemiring<T>(device const T*              inA     [[ buffer(0) ]], \
+                           device const T*              inB     [[ buffer(1) ]], \
+                           device       T*              out     [[ buffer(2) ]], \
+                           device const semiringParams& params  [[ buffer(3) ]], \
+                           device const T*              zero    [[ buffer(4) ]], \
+                           uint                         index   [[thread_position_in_grid]]);
+
+instantiateMul(float)
+instantiateMul(int)
+instantiateMul(uint)
+instantiateMul(char)
+instantiateMul(uchar)
+
+struct quantParams {
+    uint  colA;
+    uint  colB;
+    uint  strideA;
+    uint  strideB;
+    int   zeroA;
+    int   zeroB;
+    int   zeroOut;
+    int   lo;
+    int   hi;
+    float mult;
+};
+
+// mulQuantized accumulates the products of 8-bit quantized elements in
+// int and requantizes the result, see math.Requantize.
+template <typename T>
+kernel void mulQuantized(device const T*           inA     [[ buffer(0) ]],
+                         device const T*           inB     [[ buffer(1) ]],
+                         device       T*           out     [[ buffer(2) ]],
+                         device const quantParams& params  [[ buffer(3) ]],
+                         uint                      index   [[thread_position_in_grid]]) {
+
+    uint i = index / params.colB;
+    uint j = index % params.colB;
+
+    int acc = 0;
+    for (uint k = 0; k < params.colA; k++) {
+        int a = int(inA[i * params.strideA + k]) - params.zeroA;
+        int b = int(inB[k * params.strideB + j]) - params.zeroB;
+        acc += a * b;
+    }
+    int v = int(rint(float(acc) * params.mult)) + params.zeroOut;
+    out[index] = T(clamp(v, params.lo, params.hi));
+}
+
+template [[host_name("mulQuantized_char")]]
+kernel void mulQuantized<char>(device const char*        inA     [[ buffer(0) ]],
+                               device const char*        inB     [[ buffer(1) ]],
+                               device       char*        out     [[ buffer(2) ]],
+                               device const quantParams& params  [[ buffer(3) ]],
+                               uint                      index   [[thread_position_in_grid]]);
+template [[host_name("mulQuantized_uchar")]]
+kernel void mulQuantized<uchar>(device const uchar*       inA     [[ buffer(0) ]],
+                                device const uchar*       inB     [[ buffer(1) ]],
+                                device       uchar*       out     [[ buffer(2) ]],
+                                device const quantParams& params  [[ buffer(3) ]],
+                                uint                      index   [[thread_position_in_grid]]);
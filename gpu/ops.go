@@ -0,0 +1,151 @@
+// Copyright 2023 Changkun Ou <changkun.de>. All rights reserved.
+// Use of this source code is governed by a MIT license that
+// can be found in the LICENSE file.
+
+package gpu
+
+import "changkun.de/x/gogpu/math"
+
+// The following operations are processed by their math.Mat[T]
+// counterparts on the CPU when no GPU device is available or the device
+// cannot process elements of type T.
+
+const (
+	opAdd = iota
+	opSub
+	opMul
+)
+
+const (
+	opSum = iota
+	opMax
+	opMin
+	opMean
+)
+
+// Add is a GPU version of math.Mat[T].Add.
+func Add[T math.Type](m1, m2 math.Mat[T]) math.Mat[T] {
+	if !accelerated(m1, m2) {
+		return m1.Add(m2)
+	}
+	return binary(opAdd, math.TensorFromMat(m1), math.TensorFromMat(m2)).Mat()
+}
+
+// Sub is a GPU version of math.Mat[T].Sub.
+func Sub[T math.Type](m1, m2 math.Mat[T]) math.Mat[T] {
+	if !accelerated(m1, m2) {
+		return m1.Sub(m2)
+	}
+	return binary(opSub, math.TensorFromMat(m1), math.TensorFromMat(m2)).Mat()
+}
+
+// Hadamard is a GPU version of math.Mat[T].Hadamard.
+func Hadamard[T math.Type](m1, m2 math.Mat[T]) math.Mat[T] {
+	if !accelerated(m1, m2) {
+		return m1.Hadamard(m2)
+	}
+	return binary(opMul, math.TensorFromMat(m1), math.TensorFromMat(m2)).Mat()
+}
+
+// Scale is a GPU version of math.Mat[T].Scale.
+func Scale[T math.Type](m math.Mat[T], s T) math.Mat[T] {
+	if !accelerated(m) {
+		return m.Scale(s)
+	}
+	return binary(opMul, math.TensorFromMat(m), scalar(s)).Mat()
+}
+
+// Sum is a GPU version of math.Mat[T].Sum.
+func Sum[T math.Type](m math.Mat[T]) T {
+	if !accelerated(m) {
+		return m.Sum()
+	}
+	return SumAxis(m, 1).Sum()
+}
+
+// Mean is a GPU version of math.Mat[T].Mean.
+func Mean[T math.Type](m math.Mat[T]) T {
+	if !accelerated(m) {
+		return m.Mean()
+	}
+	// The division happens on the device, since T cannot be converted
+	// from an integer in generic code.
+	r, _ := reduce(opSum, m, 1, 0)
+	r, _ = reduce(opMean, r, 0, m.Row*m.Col)
+	return r.Data[0]
+}
+
+// Max is a GPU version of math.Mat[T].Max.
+func Max[T math.Type](m math.Mat[T]) T {
+	if !accelerated(m) {
+		return m.Max()
+	}
+	return MaxAxis(m, 1).Max()
+}
+
+// Min is a GPU version of math.Mat[T].Min.
+func Min[T math.Type](m math.Mat[T]) T {
+	if !accelerated(m) {
+		return m.Min()
+	}
+	return MinAxis(m, 1).Min()
+}
+
+// ArgMax is a GPU version of math.Mat[T].ArgMax.
+func ArgMax[T math.Type](m math.Mat[T]) (i, j int) {
+	if !accelerated(m) {
+		return m.ArgMax()
+	}
+	max, idx := reduce(opMax, m, 1, 0)
+	i, _ = max.ArgMax()
+	return i, idx[i]
+}
+
+// SumAxis is a GPU version of math.Mat[T].SumAxis.
+func SumAxis[T math.Type](m math.Mat[T], axis int) math.Mat[T] {
+	if !accelerated(m) {
+		return m.SumAxis(axis)
+	}
+	r, _ := reduce(opSum, m, axis, 0)
+	return r
+}
+
+// MeanAxis is a GPU version of math.Mat[T].MeanAxis.
+func MeanAxis[T math.Type](m math.Mat[T], axis int) math.Mat[T] {
+	if !accelerated(m) {
+		return m.MeanAxis(axis)
+	}
+	n := m.Row
+	if axis == 1 {
+		n = m.Col
+	}
+	r, _ := reduce(opMean, m, axis, n)
+	return r
+}
+
+// MaxAxis is a GPU version of math.Mat[T].MaxAxis.
+func MaxAxis[T math.Type](m math.Mat[T], axis int) math.Mat[T] {
+	if !accelerated(m) {
+		return m.MaxAxis(axis)
+	}
+	r, _ := reduce(opMax, m, axis, 0)
+	return r
+}
+
+// MinAxis is a GPU version of math.Mat[T].MinAxis.
+func MinAxis[T math.Type](m math.Mat[T], axis int) math.Mat[T] {
+	if !accelerated(m) {
+		return m.MinAxis(axis)
+	}
+	r, _ := reduce(opMin, m, axis, 0)
+	return r
+}
+
+// ArgMaxAxis is a GPU version of math.Mat[T].ArgMaxAxis.
+func ArgMaxAxis[T math.Type](m math.Mat[T], axis int) []int {
+	if !accelerated(m) {
+		return m.ArgMaxAxis(axis)
+	}
+	_, idx := reduce(opMax, m, axis, 0)
+	return idx
+}
@@ -0,0 +1,114 @@
+// Copyright 2023 Changkun Ou <changkun.de>. All rights reserved.
+// Use of this source code is governed by a MIT license that
+// can be found in the LICENSE file.
+
+#include <metal_stdlib>
+using namespace metal;
+
+struct binaryParams {
+    uint op;
+    uint ndim;
+    uint shape[8];
+    uint strideA[8];
+    uint strideB[8];
+};
+
+template <typename T>
+kernel void binary(device const T*            inA     [[ buffer(0) ]],
+                   device const T*            inB     [[ buffer(1) ]],
+                   device       T*            out     [[ buffer(2) ]],
+                   device const binaryParams& params  [[ buffer(3) ]],
+                   uint                       index   [[thread_position_in_grid]]) {
+
+    // Decompose the row-major index of the output into the
+    // positions of the two inputs.
+    uint rem = index;
+    uint ia = 0;
+    uint ib = 0;
+    for (int d = int(params.ndim) - 1; d >= 0; d--) {
+        uint k = rem % params.shape[d];
+        rem /= params.shape[d];
+        ia += k * params.strideA[d];
+        ib += k * params.strideB[d];
+    }
+
+    T a = inA[ia];
+    T b = inB[ib];
+    switch (params.op) {
+    case 0:
+        out[index] = a + b;
+        break;
+    case 1:
+        out[index] = a - b;
+        break;
+    case 2:
+        out[index] = a * b;
+        break;
+    }
+}
+
+struct reduceParams {
+    uint op;
+    uint n;
+    uint outerStride;
+    uint innerStride;
+    uint div;
+};
+
+template <typename T>
+kernel void reduce(device const T*            in      [[ buffer(0) ]],
+                   device       T*            out     [[ buffer(1) ]],
+                   device       uint*         arg     [[ buffer(2) ]],
+                   device const reduceParams& params  [[ buffer(3) ]],
+                   uint                       index   [[thread_position_in_grid]]) {
+
+    uint base = index * params.outerStride;
+    T acc = in[base];
+    uint at = 0;
+    for (uint k = 1; k < params.n; k++) {
+        T v = in[base + k * params.innerStride];
+        switch (params.op) {
+        case 0:
+        case 3:
+            acc += v;
+            break;
+        case 1:
+            if (v > acc) {
+                acc = v;
+                at = k;
+            }
+            break;
+        case 2:
+            if (v < acc) {
+                acc = v;
+                at = k;
+            }
+            break;
+        }
+    }
+    if (params.op == 3) {
+        acc /= T(params.div);
+    }
+    out[index] = acc;
+    arg[index] = at;
+}
+
+#define instantiateOps(T)                                                           \
+template [[host_name("binary_" #T)]]                                                \
+kernel void binary<T>(device const T*            inA     [[ buffer(0) ]],           \
+                      device const T*            inB     [[ buffer(1) ]],           \
+                      device       T*            out     [[ buffer(2) ]],           \
+                      device const binaryParams& params  [[ buffer(3) ]],           \
+                      uint                       index   [[thread_position_in_grid]]); \
+template [[host_name("reduce_" #T)]]                                                \
+kernel void reduce<T>(device const T*            in      [[ buffer(0) ]],           \
+                      device       T*            out     [[ buffer(1) ]],           \
+                      device       uint*         arg     [[ buffer(2) ]],           \
+                      device const reduceParams& params  [[ buffer(3) ]],           \
+                      uint                       index   [[thread_position_in_grid]]);
+
+instantiateOps(float)
+instantiateOps(int)
+instantiateOps(uint)
+instantiateOps(char)
+instantiateOps(uchar)
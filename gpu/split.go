@@ -0,0 +1,150 @@
+// Copyright 2023 Changkun Ou <changkun.de>. All rights reserved.
+// Use of this source code is governed by a MIT license that
+// can be found in the LICENSE file.
+
+package gpu
+
+import (
+	"runtime"
+	"sync"
+	"time"
+
+	"changkun.de/x/gogpu/math"
+)
+
+// Scheduler splits the output rows of a multiplication between the GPU
+// device and the CPU, which compute their parts at the same time. This
+// keeps the CPU cores busy on a unified-memory machine, where both
+// share the same memory.
+//
+// The split ratio follows the measured throughput of both sides, hence
+// the time of both parts converges over successive multiplications and
+// adapts if the load of either side changes. A Scheduler is safe for
+// concurrent use.
+type Scheduler[T math.Type] struct {
+	device, host math.MulFunc[T]
+
+	mu    sync.Mutex
+	ratio float64    // fraction of the rows multiplied by device
+	rate  [2]float64 // smoothed throughput of device and host
+}
+
+// schedulerSmoothing is the weight of a new throughput measurement
+// against the previous ones.
+const schedulerSmoothing = 0.5
+
+// NewScheduler returns a scheduler that splits the rows between the
+// multiplications device and host, which starts with an even split. A
+// nil device multiplies by Mul, and a nil host multiplies by
+// math.Mat[T].Mul in parallel on all CPUs.
+func NewScheduler[T math.Type](device, host math.MulFunc[T]) *Scheduler[T] {
+	if device == nil {
+		device = Mul[T]
+	}
+	if host == nil {
+		host = mulParallel[T]
+	}
+	return &Scheduler[T]{device: device, host: host, ratio: 0.5}
+}
+
+// Ratio returns the current fraction of the rows that are multiplied
+// by the device.
+func (s *Scheduler[T]) Ratio() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ratio
+}
+
+// Mul multiplies m1 and m2, where the first rows of the product are
+// computed by the device and the remaining rows by the host. Both sides
+// get at least one row if the product has more than one row. A panic of
+// the device is raised again by Mul once the host finishes.
+func (s *Scheduler[T]) Mul(m1, m2 math.Mat[T]) math.Mat[T] {
+	if m1.Col != m2.Row {
+		panic("math: mismatched matrix dimension")
+	}
+
+	n := m1.Row
+	k := int(float64(n)*s.Ratio() + 0.5)
+	if n > 1 && k < 1 {
+		k = 1
+	} else if n > 1 && k > n-1 {
+		k = n - 1
+	}
+
+	var (
+		top, bottom math.Mat[T]
+		elapsed     [2]time.Duration
+		failure     any
+		wg          sync.WaitGroup
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer func() { failure = recover() }()
+		t := time.Now()
+		top = s.device(m1.Slice(0, k, 0, m1.Col), m2)
+		elapsed[0] = time.Since(t)
+	}()
+	t := time.Now()
+	bottom = s.host(m1.Slice(k, n, 0, m1.Col), m2)
+	elapsed[1] = time.Since(t)
+	wg.Wait()
+	if failure != nil {
+		panic(failure)
+	}
+
+	work := float64(m1.Col * m2.Col)
+	s.update([2]float64{float64(k) * work, float64(n-k) * work}, elapsed)
+
+	r := math.Mat[T]{Row: n, Col: m2.Col, Data: make([]T, 0, n*m2.Col)}
+	r.Data = append(r.Data, top.Clone().Data...)
+	r.Data = append(r.Data, bottom.Clone().Data...)
+	return r
+}
+
+// update smooths the throughput of both sides by the time that they
+// took for the given number of multiply-adds, and sets the ratio such
+// that both sides are expected to finish at the same time.
+func (s *Scheduler[T]) update(work [2]float64, elapsed [2]time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range work {
+		if work[i] == 0 || elapsed[i] <= 0 {
+			return // not measured
+		}
+	}
+	for i := range work {
+		rate := work[i] / elapsed[i].Seconds()
+		if s.rate[i] == 0 {
+			s.rate[i] = rate
+		} else {
+			s.rate[i] += schedulerSmoothing * (rate - s.rate[i])
+		}
+	}
+	s.ratio = s.rate[0] / (s.rate[0] + s.rate[1])
+}
+
+// mulParallel multiplies blocks of rows of m1 by m2 in parallel.
+func mulParallel[T math.Type](m1, m2 math.Mat[T]) math.Mat[T] {
+	n := runtime.GOMAXPROCS(0)
+	if n > m1.Row {
+		n = m1.Row
+	}
+	if n <= 1 {
+		return m1.Mul(m2)
+	}
+
+	as := make([]math.Mat[T], n)
+	bs := make([]math.Mat[T], n)
+	for i := range as {
+		as[i] = m1.Slice(i*m1.Row/n, (i+1)*m1.Row/n, 0, m1.Col)
+		bs[i] = m2
+	}
+	r := math.Mat[T]{Row: m1.Row, Col: m2.Col, Data: make([]T, 0, m1.Row*m2.Col)}
+	for _, p := range math.MulBatched(as, bs) {
+		r.Data = append(r.Data, p.Data...)
+	}
+	return r
+}
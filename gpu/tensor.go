@@ -0,0 +1,74 @@
+// Copyright 2023 Changkun Ou <changkun.de>. All rights reserved.
+// Use of this source code is governed by a MIT license that
+// can be found in the LICENSE file.
+
+package gpu
+
+import "changkun.de/x/gogpu/math"
+
+// maxDim is the maximum number of tensor dimensions supported by
+// the GPU kernels.
+const maxDim = 8
+
+// TensorAdd is a GPU version of math.Tensor[T].Add.
+func TensorAdd[T math.Type](t1, t2 math.Tensor[T]) math.Tensor[T] {
+	if !acceleratedTensor(t1, t2) {
+		return t1.Add(t2)
+	}
+	return binary(opAdd, t1, t2)
+}
+
+// TensorSub is a GPU version of math.Tensor[T].Sub.
+func TensorSub[T math.Type](t1, t2 math.Tensor[T]) math.Tensor[T] {
+	if !acceleratedTensor(t1, t2) {
+		return t1.Sub(t2)
+	}
+	return binary(opSub, t1, t2)
+}
+
+// TensorHadamard is a GPU version of math.Tensor[T].Hadamard.
+func TensorHadamard[T math.Type](t1, t2 math.Tensor[T]) math.Tensor[T] {
+	if !acceleratedTensor(t1, t2) {
+		return t1.Hadamard(t2)
+	}
+	return binary(opMul, t1, t2)
+}
+
+// TensorScale is a GPU version of math.Tensor[T].Scale.
+func TensorScale[T math.Type](t math.Tensor[T], s T) math.Tensor[T] {
+	if !acceleratedTensor(t) {
+		return t.Scale(s)
+	}
+	return binary(opMul, t, scalar(s))
+}
+
+// acceleratedTensor reports whether an operation on the given
+// tensors can be executed by the GPU kernels.
+func acceleratedTensor[T math.Type](ts ...math.Tensor[T]) bool {
+	if !Supports[T]() {
+		return false
+	}
+	for _, t := range ts {
+		if t.Size() == 0 || t.Dim() > maxDim {
+			return false
+		}
+	}
+	return true
+}
+
+// spanTensor returns the number of elements in Data that are covered by t.
+func spanTensor[T math.Type](t math.Tensor[T]) int {
+	if t.Size() == 0 {
+		return 0
+	}
+	n := 1
+	for i, v := range t.Shape {
+		n += (v - 1) * t.Strides[i]
+	}
+	return n
+}
+
+// scalar returns a 0-D tensor that holds the given value.
+func scalar[T math.Type](v T) math.Tensor[T] {
+	return math.Tensor[T]{Data: []T{v}}
+}
@@ -0,0 +1,183 @@
+// Copyright 2023 Changkun Ou <changkun.de>. All rights reserved.
+// Use of this source code is governed by a MIT license that
+// can be found in the LICENSE file.
+
+package gpu
+
+import "changkun.de/x/gogpu/math"
+
+// Backend is a device that multiplies the tiles of MulBudget. The
+// memory of a device is addressable by the CPU, as is the shared
+// storage of Metal, hence tiles are uploaded and downloaded by copying
+// their elements from and to the matrices that Alloc returns.
+//
+// Alloc and Free may be called concurrently with MulAdd.
+type Backend[T math.Type] interface {
+	// Alloc allocates a dense zero matrix in the device memory.
+	Alloc(row, col int) math.Mat[T]
+	// Free releases a matrix returned by Alloc.
+	Free(m math.Mat[T])
+	// MulAdd accumulates the product a*b into acc, all of which are
+	// returned by Alloc.
+	MulAdd(acc, a, b math.Mat[T])
+}
+
+// CPU returns the backend that multiplies tiles on the CPU by
+// math.Mat[T].Mul.
+func CPU[T math.Type]() Backend[T] { return cpu[T]{} }
+
+type cpu[T math.Type] struct{}
+
+func (cpu[T]) Alloc(row, col int) math.Mat[T] {
+	return math.Mat[T]{Row: row, Col: col, Data: make([]T, row*col)}
+}
+
+func (cpu[T]) Free(m math.Mat[T]) {}
+
+func (cpu[T]) MulAdd(acc, a, b math.Mat[T]) {
+	p := a.Mul(b)
+	for i, v := range p.Data {
+		acc.Data[i] += v
+	}
+}
+
+// DeviceBackend returns the backend of the GPU device, or the CPU
+// backend if no GPU device is available or the device cannot process
+// elements of type T.
+func DeviceBackend[T math.Type]() Backend[T] {
+	if !Supports[T]() {
+		return CPU[T]()
+	}
+	return newDeviceBackend[T]()
+}
+
+// MulBudget computes dst = m1*m2 in tiles, such that at most budget
+// bytes of the device memory of the backend are allocated at once,
+// which allows to multiply matrices that do not fit into the device
+// memory. A nil backend is the DeviceBackend. Unlike math.MulTiled,
+// whose tiles have a fixed edge, the tiles are the largest that fit
+// into the budget.
+//
+// The product is accumulated from the partial products of the blocks
+// of m1 and m2 in a device matrix for each tile of dst. The blocks are
+// uploaded while the previous pair of blocks is multiplied, hence the
+// budget holds two pairs of blocks and one tile of the product.
+//
+// The matrices may be views or mapped files, see math.OpenMapped. It
+// panics if the budget cannot hold a single element of each block.
+func MulBudget[T math.Type](dst, m1, m2 math.Mat[T], budget int, be Backend[T]) {
+	if m1.Col != m2.Row || dst.Row != m1.Row || dst.Col != m2.Col {
+		panic("math: mismatched matrix dimension")
+	}
+	if be == nil {
+		be = DeviceBackend[T]()
+	}
+	tm, tk, tn := tileSize(m1.Row, m1.Col, m2.Col, budget/math.TypeSize[T]())
+	if m1.Col == 0 {
+		for i := 0; i < dst.Row; i++ {
+			for j := 0; j < dst.Col; j++ {
+				dst.Set(i, j, 0)
+			}
+		}
+		return
+	}
+
+	type step struct {
+		i0, i1, j0, j1, k0, k1 int
+		a, b                   math.Mat[T]
+	}
+
+	// The unbuffered channel double-buffers the blocks: the next pair of
+	// blocks is uploaded while the current pair is multiplied, and is
+	// only received after the current pair is freed.
+	steps := make(chan step)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		defer close(steps)
+		math.Blocks(m1.Row, m1.Col, m2.Col, tm, tk, tn, func(i0, i1, j0, j1, k0, k1 int) bool {
+			s := step{i0, i1, j0, j1, k0, k1,
+				stage(be, m1.Slice(i0, i1, k0, k1)),
+				stage(be, m2.Slice(k0, k1, j0, j1))}
+			select {
+			case steps <- s:
+				return true
+			case <-done:
+				be.Free(s.a)
+				be.Free(s.b)
+				return false
+			}
+		})
+	}()
+
+	var acc math.Mat[T]
+	for s := range steps {
+		if s.k0 == 0 {
+			acc = be.Alloc(s.i1-s.i0, s.j1-s.j0)
+		}
+		be.MulAdd(acc, s.a, s.b)
+		be.Free(s.a)
+		be.Free(s.b)
+		if s.k1 == m1.Col {
+			d := dst.Slice(s.i0, s.i1, s.j0, s.j1)
+			for i := 0; i < acc.Row; i++ {
+				copy(d.Data[d.Index(i, 0):d.Index(i, acc.Col)], acc.Data[i*acc.Col:(i+1)*acc.Col])
+			}
+			be.Free(acc)
+		}
+	}
+}
+
+// stage copies a block to a new device matrix.
+func stage[T math.Type](be Backend[T], m math.Mat[T]) math.Mat[T] {
+	d := be.Alloc(m.Row, m.Col)
+	for i := 0; i < m.Row; i++ {
+		copy(d.Data[i*m.Col:(i+1)*m.Col], m.Data[m.Index(i, 0):m.Index(i, m.Col)])
+	}
+	return d
+}
+
+// tileSize returns the largest tiles for the product of an m x k and a
+// k x n matrix, such that two pairs of blocks and a tile of the product
+// take at most budget elements.
+func tileSize(m, k, n, budget int) (tm, tk, tn int) {
+	size := func(t int) (int, int, int, int) {
+		tm, tk, tn := clamp(t, m), clamp(t, k), clamp(t, n)
+		return tm, tk, tn, 2*(tm*tk+tk*tn) + tm*tn
+	}
+	if _, _, _, s := size(1); s > budget {
+		panic("gpu: memory budget is too small for a single tile")
+	}
+
+	// Find the largest square tile by bisection, as the size grows
+	// monotonically with the tile.
+	lo, hi := 1, max(m, max(k, n))+1
+	for lo+1 < hi {
+		mid := lo + (hi-lo)/2
+		if _, _, _, s := size(mid); s <= budget {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	tm, tk, tn, _ = size(lo)
+	return tm, tk, tn
+}
+
+// clamp returns t limited to [1, n], or 1 if n is zero.
+func clamp(t, n int) int {
+	if t > n {
+		t = n
+	}
+	if t < 1 {
+		t = 1
+	}
+	return t
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
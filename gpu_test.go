@@ -5,13 +5,17 @@
 package main_test
 
 import (
+	"errors"
 	"fmt"
 	"image"
 	"image/draw"
 	"image/jpeg"
 	"log"
+	stdmath "math"
 	"os"
+	"sync"
 	"testing"
+	"time"
 
 	"changkun.de/x/gogpu/enhance"
 	"changkun.de/x/gogpu/gpu"
@@ -103,8 +107,16 @@ func TestMul(t *testing.T) {
 			},
 		},
 		{
-			m1: math.NewRandMat[float32](7, 6),
-			m2: math.NewRandMat[float32](6, 3),
+			m1: math.NewRandMat[float32](7, 6, math.WithSeed(1)),
+			m2: math.NewRandMat[float32](6, 3, math.WithSeed(2)),
+		},
+		{
+			m1: math.NewRandMat[float32](8, 8, math.WithSeed(3)).Slice(1, 5, 2, 7),
+			m2: math.NewRandMat[float32](9, 6, math.WithSeed(4)).Slice(2, 7, 0, 3),
+		},
+		{
+			m1: math.NewRandMat[float32](5, 4, math.WithSeed(5)).View().T().Mat(),
+			m2: math.NewRandMat[float32](6, 8, math.WithSeed(6)).View().Slice(0, 5, 1, 7).Col(2).Mat(),
 		},
 	}
 
@@ -125,8 +137,8 @@ func BenchmarkMul(b *testing.B) {
 	}
 
 	for size := 1 << 1; size < 2<<13; size *= 2 {
-		m1 := math.NewRandMat[float32](size, size)
-		m2 := math.NewRandMat[float32](size, size)
+		m1 := math.NewRandMat[float32](size, size, math.WithSeed(1))
+		m2 := math.NewRandMat[float32](size, size, math.WithSeed(2))
 
 		var outGPU math.Mat[float32]
 		var outCPU math.Mat[float32]
@@ -147,8 +159,543 @@ func BenchmarkMul(b *testing.B) {
 			}
 		})
 
-		if !outCPU.Eq(outGPU) {
-			b.Fatal("inconsistent results")
+		// The float32 sums grow with the size, hence the results are
+		// compared with a relative tolerance.
+		if r := math.Compare(outGPU, outCPU, math.CompareOptions{Rel: 1e-4}); !r.Equal() {
+			b.Fatalf("inconsistent results: %v", r)
+		}
+	}
+}
+
+func TestMulUnsupportedType(t *testing.T) {
+	m1 := math.NewRandMat[float64](5, 3, math.WithSeed(7))
+	m2 := math.NewRandMat[float64](3, 4, math.WithSeed(8))
+	if gpu.Supports[float64]() {
+		t.Skip("float64 is supported by the device")
+	}
+
+	// Unlike Mul, the other operations fall back to the CPU.
+	if got, want := gpu.Add(m1, m1), m1.Add(m1); !got.Eq(want) {
+		t.Fatalf("Add: CPU fallback receives different results: got %v, want %v", got, want)
+	}
+	if got, want := gpu.SumAxis(m1, 0), m1.SumAxis(0); !got.Eq(want) {
+		t.Fatalf("SumAxis: CPU fallback receives different results: got %v, want %v", got, want)
+	}
+	if got, want := gpu.MulBatched([]math.Mat[float64]{m1}, []math.Mat[float64]{m2}), m1.MulNaive(m2); !got[0].Eq(want) {
+		t.Fatalf("MulBatched: CPU fallback receives different results: got %v, want %v", got[0], want)
+	}
+
+	defer func() {
+		r := recover()
+		if !gpu.Driver().Available() {
+			if r != nil {
+				t.Fatalf("unexpected panic without a device: %v", r)
+			}
+			return
+		}
+		if err, ok := r.(error); !ok || !errors.Is(err, gpu.ErrUnsupportedType) {
+			t.Fatalf("want ErrUnsupportedType, got %v", r)
+		}
+	}()
+	if got, want := gpu.Mul(m1, m2), m1.MulNaive(m2); !got.Eq(want) {
+		t.Fatalf("CPU fallback receives different results: got %v, want %v", got, want)
+	}
+}
+
+func TestMulMixed(t *testing.T) {
+	testMulMixed[math.Float16](t, 1e-3)
+	testMulMixed[math.BFloat16](t, 1e-2)
+}
+
+func testMulMixed[T math.Half](t *testing.T, tol float64) {
+	t.Helper()
+
+	m1 := math.NewRandMat[float32](33, 17, math.WithSeed(9))
+	m2 := math.NewRandMat[float32](17, 21, math.WithSeed(10))
+	h1 := math.FromFloat32[T](m1)
+	h2 := math.FromFloat32[T](m2).Slice(0, 17, 3, 20)
+
+	// The product of the rounded inputs is computed in float32,
+	// whereas the rounding of the inputs limits the accuracy.
+	want := math.ToFloat32(h1).MulNaive(math.ToFloat32(h2))
+	for _, got := range []math.Mat[float32]{math.MulMixed(h1, h2), gpu.MulMixed(h1, h2)} {
+		if !got.Eq(want) {
+			t.Fatalf("%T: different results compare to MulNaive: got %v, want %v", h1.Data[0], got, want)
+		}
+	}
+	exact := m1.MulNaive(m2.Slice(0, 17, 3, 20))
+	for i := range exact.Data {
+		if d := stdmath.Abs(float64(exact.Data[i] - want.Data[i])); d > tol*float64(exact.Data[i]) {
+			t.Fatalf("%T: inaccurate result %v, want %v", h1.Data[0], want.Data[i], exact.Data[i])
+		}
+	}
+}
+
+func TestMulQuantized(t *testing.T) {
+	testMulQuantized[int8](t)
+	testMulQuantized[uint8](t)
+}
+
+func testMulQuantized[T math.Q8](t *testing.T) {
+	t.Helper()
+
+	m1 := math.NewRandMat[float32](31, 64, math.WithSeed(11)).Apply(func(v float32) float32 { return 2*v - 0.5 })
+	m2 := math.NewRandMat[float32](64, 40, math.WithSeed(12)).Apply(func(v float32) float32 { return 1 - v })
+	q1, q2 := math.QuantizeFrom[T](m1), math.QuantizeFrom[T](m2)
+	q2.Mat = q2.Mat.Slice(0, 64, 5, 37)
+
+	// The accumulation is exact, hence all backends agree on the
+	// requantized result.
+	want := math.QuantizeFrom[T](q1.Dequantize().MulNaive(q2.Dequantize()))
+	lo, hi := math.QuantRange[T]()
+	for i := 0; i < want.Mat.Row; i++ {
+		for j := 0; j < want.Mat.Col; j++ {
+			var acc int32
+			for k := 0; k < q1.Mat.Col; k++ {
+				acc += (int32(q1.Mat.Get(i, k)) - q1.ZeroPoint) * (int32(q2.Mat.Get(k, j)) - q2.ZeroPoint)
+			}
+			want.Mat.Set(i, j, T(math.Requantize(acc, q1.Scale*q2.Scale/want.Scale, want.ZeroPoint, lo, hi)))
+		}
+	}
+	for _, got := range []math.QMat[T]{
+		math.MulQuantized(q1, q2, want.Scale, want.ZeroPoint),
+		gpu.MulQuantized(q1, q2, want.Scale, want.ZeroPoint),
+	} {
+		if !got.Mat.Eq(want.Mat) {
+			t.Fatalf("%T: different results: got %v, want %v", want.Mat.Data[0], got.Mat, want.Mat)
+		}
+	}
+
+	// The quantized product approximates the float32 product.
+	exact := m1.MulNaive(m2.Slice(0, 64, 5, 37))
+	got := math.MulQuantized(q1, q2, want.Scale, want.ZeroPoint).Dequantize()
+	tol := float64(want.Scale + q1.Scale*64 + q2.Scale*64)
+	for i := range exact.Data {
+		if e := stdmath.Abs(float64(got.Data[i] - exact.Data[i])); e > tol {
+			t.Fatalf("%T: error %v exceeds %v", want.Mat.Data[0], e, tol)
+		}
+	}
+}
+
+func TestMulSemiring(t *testing.T) {
+	// All-pairs shortest paths of a random graph by repeated squaring
+	// of its distance matrix agree with Floyd-Warshall. The integer
+	// weights make the lengths exact regardless of the order of the
+	// additions.
+	const n = 30
+	inf := float32(stdmath.Inf(1))
+	dist := math.NewRandMat[float32](n, n, math.WithSeed(13)).Apply(func(v float32) float32 {
+		if v < 0.8 {
+			return inf
+		}
+		return float32(int(100*v) - 79)
+	})
+	for i := 0; i < n; i++ {
+		dist.Set(i, i, 0)
+	}
+	want := dist.Clone()
+	for k := 0; k < n; k++ {
+		for i := 0; i < n; i++ {
+			for j := 0; j < n; j++ {
+				if d := want.Get(i, k) + want.Get(k, j); d < want.Get(i, j) {
+					want.Set(i, j, d)
+				}
+			}
+		}
+	}
+	for _, mul := range []func(m1, m2 math.Mat[float32], sr math.Semiring[float32]) math.Mat[float32]{
+		math.MulSemiring[float32],
+		gpu.MulSemiring[float32],
+	} {
+		got := dist
+		for l := 1; l < n; l *= 2 {
+			got = mul(got, got, math.MinPlus[float32]())
+		}
+		if !got.Eq(want) {
+			t.Fatalf("MinPlus: got %v, want %v", got, want)
+		}
+	}
+
+	m1 := math.NewRandMat[float32](13, 9, math.IntRange(0, 10), math.WithSeed(14))
+	m2 := math.NewRandMat[float32](9, 11, math.IntRange(0, 10), math.WithSeed(15))
+	i1 := math.Mat[int32]{Row: 13, Col: 9, Data: make([]int32, 13*9)}
+	for i, v := range m1.Data {
+		i1.Data[i] = int32(v) - 3
+	}
+	u1 := math.Mat[uint8]{Row: 13, Col: 9, Data: make([]uint8, 13*9)}
+	for i, v := range m1.Data {
+		u1.Data[i] = uint8(v) / 5
+	}
+	u2 := math.Mat[uint8]{Row: 9, Col: 11, Data: make([]uint8, 9*11)}
+	for i, v := range m2.Data {
+		u2.Data[i] = uint8(v) / 5
+	}
+	reach := u1.MulNaive(u2).Apply(func(v uint8) uint8 {
+		if v > 0 {
+			return 1
+		}
+		return 0
+	})
+	maxTimes := math.Semiring[float32]{
+		Add: func(a, b float32) float32 { return float32(stdmath.Max(float64(a), float64(b))) },
+		Mul: func(a, b float32) float32 { return a * b },
+	}
+
+	tests := []struct {
+		got, want any
+	}{
+		{gpu.MulSemiring(m1, m2, math.PlusTimes[float32]()), m1.MulNaive(m2)},
+		{gpu.MulSemiring(m1, m2.Slice(0, 9, 2, 8), maxTimes), mulSemiringNaive(m1, m2.Slice(0, 9, 2, 8), maxTimes)},
+		{gpu.MulSemiring(i1, i1.View().T().Mat(), math.MaxPlus[int32]()), mulSemiringNaive(i1, i1.View().T().Mat(), math.MaxPlus[int32]())},
+		{gpu.MulSemiring(u1, u2, math.OrAnd[uint8]()), reach},
+		{math.MulSemiring(u1, u2, math.OrAnd[uint8]()), reach},
+	}
+	for i, tt := range tests {
+		if fmt.Sprint(tt.got) != fmt.Sprint(tt.want) {
+			t.Fatalf("#%d: got %v, want %v", i, tt.got, tt.want)
+		}
+	}
+
+	// The minimum 0 of an unsigned type is a valid weight, which the
+	// max-plus semiring must not absorb: 0 ⊗ 5 is 5.
+	for name, maxPlus := range map[string]func(){
+		"uint8":  func() { math.MaxPlus[uint8]() },
+		"uint32": func() { math.MaxPlus[uint32]() },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("MaxPlus[%s]: want a panic", name)
+				}
+			}()
+			maxPlus()
+		}()
+	}
+	if mp := math.MaxPlus[int8](); mp.Mul(0, 5) != 5 || mp.Mul(mp.Zero, 5) != mp.Zero {
+		t.Fatalf("MaxPlus[int8]: 0 ⊗ 5 is %v, want 5", mp.Mul(0, 5))
+	}
+}
+
+// mulSemiringNaive is the reference semiring multiplication.
+func mulSemiringNaive[T math.Type](m, n math.Mat[T], sr math.Semiring[T]) math.Mat[T] {
+	r := math.Mat[T]{Row: m.Row, Col: n.Col, Data: make([]T, m.Row*n.Col)}
+	for i := 0; i < m.Row; i++ {
+		for j := 0; j < n.Col; j++ {
+			acc := sr.Zero
+			for k := 0; k < m.Col; k++ {
+				acc = sr.Add(acc, sr.Mul(m.Get(i, k), n.Get(k, j)))
+			}
+			r.Set(i, j, acc)
+		}
+	}
+	return r
+}
+
+func TestMulTiled(t *testing.T) {
+	m1 := math.NewRandMat[float32](37, 50, math.WithSeed(1))
+	m2 := math.NewRandMat[float32](50, 29, math.WithSeed(2))
+	want := m1.MulNaive(m2)
+
+	for _, mul := range []math.MulFunc[float32]{nil, gpu.Mul[float32]} {
+		for _, tile := range []int{1, 8, 16, 64} {
+			got := math.Zeros[float32](37, 29)
+			math.MulTiled(got, m1, m2, tile, mul)
+			if r := math.Compare(got, want, math.CompareOptions{Rel: 1e-4}); !r.Equal() {
+				t.Fatalf("tile %d: %v", tile, r)
+			}
+		}
+	}
+
+	// The operands and the product may be views, e.g. of mapped files.
+	dst := math.Zeros[int32](10, 10)
+	a := math.NewRandMat[int32](12, 9, math.WithSeed(16))
+	b := math.NewRandMat[int32](9, 8, math.WithSeed(17))
+	math.MulTiled(dst.Slice(1, 8, 2, 9), a.Slice(2, 9, 1, 8), b.Slice(1, 8, 0, 7), 3, gpu.Mul[int32])
+	want32 := math.Zeros[int32](10, 10)
+	copyInto(want32.Slice(1, 8, 2, 9), a.Slice(2, 9, 1, 8).Clone().MulNaive(b.Slice(1, 8, 0, 7).Clone()))
+	if !dst.Eq(want32) {
+		t.Fatalf("views: got %v, want %v", dst, want32)
+	}
+
+	// An empty inner dimension results in a zero product.
+	ones := math.Mat[float32]{Row: 3, Col: 2, Data: []float32{1, 1, 1, 1, 1, 1}}
+	math.MulTiled(ones, math.Zeros[float32](3, 0), math.Zeros[float32](0, 2), 2, nil)
+	if !ones.Eq(math.Zeros[float32](3, 2)) {
+		t.Fatalf("empty: got %v, want zeros", ones)
+	}
+}
+
+// budgetBackend is a CPU backend that records the peak of its allocated
+// memory, and whether blocks are uploaded during a multiplication.
+type budgetBackend struct {
+	gpu.Backend[float32]
+
+	mu         sync.Mutex
+	live, peak int
+	computing  bool
+	overlapped bool
+}
+
+func (be *budgetBackend) Alloc(row, col int) math.Mat[float32] {
+	be.mu.Lock()
+	defer be.mu.Unlock()
+	be.live += 4 * row * col
+	if be.live > be.peak {
+		be.peak = be.live
+	}
+	if be.computing {
+		be.overlapped = true
+	}
+	return be.Backend.Alloc(row, col)
+}
+
+func (be *budgetBackend) Free(m math.Mat[float32]) {
+	be.mu.Lock()
+	be.live -= 4 * m.Row * m.Col
+	be.mu.Unlock()
+	be.Backend.Free(m)
+}
+
+func (be *budgetBackend) MulAdd(acc, a, b math.Mat[float32]) {
+	be.mu.Lock()
+	be.computing = true
+	be.mu.Unlock()
+	time.Sleep(time.Millisecond) // an artificially slow device
+	be.Backend.MulAdd(acc, a, b)
+	be.mu.Lock()
+	be.computing = false
+	be.mu.Unlock()
+}
+
+func TestMulBudget(t *testing.T) {
+	m1 := math.NewRandMat[float32](70, 45, math.WithSeed(1))
+	m2 := math.NewRandMat[float32](45, 33, math.WithSeed(2))
+	want := m1.MulNaive(m2)
+
+	for _, budget := range []int{4 * 5 * 8 * 8, 4 * 5 * 16 * 16, 1 << 20} {
+		be := &budgetBackend{Backend: gpu.CPU[float32]()}
+		got := math.Zeros[float32](70, 33)
+		gpu.MulBudget[float32](got, m1, m2, budget, be)
+		if r := math.Compare(got, want, math.CompareOptions{Rel: 1e-4}); !r.Equal() {
+			t.Fatalf("budget %d: %v", budget, r)
+		}
+		if be.peak > budget || be.live != 0 {
+			t.Fatalf("budget %d: peak %d, %d bytes leaked", budget, be.peak, be.live)
+		}
+		if budget < 1<<20 && !be.overlapped {
+			t.Fatalf("budget %d: blocks are not uploaded during multiplication", budget)
+		}
+	}
+
+	// The operands may be views, and the backend defaults to the device.
+	dst := math.Zeros[int32](10, 10)
+	a := math.NewRandMat[int32](12, 9, math.WithSeed(18))
+	b := math.NewRandMat[int32](9, 8, math.WithSeed(19))
+	gpu.MulBudget(dst.Slice(1, 8, 2, 9), a.Slice(2, 9, 1, 8), b.Slice(1, 8, 0, 7), 4*5*3*3, nil)
+	want32 := math.Zeros[int32](10, 10)
+	copyInto(want32.Slice(1, 8, 2, 9), a.Slice(2, 9, 1, 8).Clone().MulNaive(b.Slice(1, 8, 0, 7).Clone()))
+	if !dst.Eq(want32) {
+		t.Fatalf("views: got %v, want %v", dst, want32)
+	}
+
+	// Mul tiles its operands in the configured budget.
+	gpu.SetMemoryBudget(4 * 5 * 8 * 8)
+	got := gpu.Mul(m1, m2)
+	gpu.SetMemoryBudget(0)
+	if r := math.Compare(got, want, math.CompareOptions{Rel: 1e-4}); !r.Equal() {
+		t.Fatalf("SetMemoryBudget: %v", r)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("want a panic for a budget smaller than a tile")
+		}
+	}()
+	gpu.MulBudget(math.Zeros[float32](2, 2), m1.Slice(0, 2, 0, 2), m2.Slice(0, 2, 0, 2), 19, gpu.CPU[float32]())
+}
+
+func TestScheduler(t *testing.T) {
+	// Two CPU backends whose speed is given by the time per row.
+	slowed := func(perRow *time.Duration) math.MulFunc[int32] {
+		return func(m1, m2 math.Mat[int32]) math.Mat[int32] {
+			time.Sleep(time.Duration(m1.Row) * *perRow)
+			return m1.Mul(m2)
+		}
+	}
+	fast, slow := 50*time.Microsecond, 150*time.Microsecond
+	s := gpu.NewScheduler(slowed(&fast), slowed(&slow))
+
+	run := func(n int) {
+		for i := 0; i < n; i++ {
+			m1 := math.NewRandMat[int32](64, 16, math.WithSeed(int64(i)))
+			m2 := math.NewRandMat[int32](16, 24, math.WithSeed(int64(-i)))
+			if got, want := s.Mul(m1, m2), m1.MulNaive(m2); !got.Eq(want) {
+				t.Fatalf("got %v, want %v", got, want)
+			}
+		}
+	}
+	if r := s.Ratio(); r != 0.5 {
+		t.Fatalf("initial ratio: got %v, want 0.5", r)
+	}
+	// The sleeps take longer on a loaded machine, hence only the side
+	// that gets more rows is checked rather than the ratio of 0.75.
+	run(15)
+	faster := s.Ratio()
+	if faster <= 0.5 {
+		t.Fatalf("ratio with a 3x faster device: got %v, want more than 0.5", faster)
+	}
+
+	// The ratio adapts if the device becomes slower than the host.
+	fast, slow = slow, fast
+	run(15)
+	if r := s.Ratio(); r >= 0.5 || r >= faster {
+		t.Fatalf("ratio with a 3x slower device: got %v, want less than 0.5", r)
+	}
+
+	// A panic of the device is raised on the goroutine of the caller.
+	func() {
+		failing := gpu.NewScheduler(func(m1, m2 math.Mat[int32]) math.Mat[int32] {
+			panic("device failure")
+		}, nil)
+		defer func() {
+			if r := recover(); r != "device failure" {
+				t.Fatalf("want the panic of the device, got %v", r)
+			}
+		}()
+		failing.Mul(math.Zeros[int32](4, 2), math.Zeros[int32](2, 3))
+	}()
+
+	d := gpu.NewScheduler[float32](nil, nil)
+	for _, size := range [][3]int{{1, 5, 3}, {2, 3, 4}, {37, 20, 11}, {200, 64, 64}} {
+		m1 := math.NewRandMat[float32](size[0], size[1], math.WithSeed(20))
+		m2 := math.NewRandMat[float32](size[1], size[2], math.WithSeed(21))
+		got, want := d.Mul(m1, m2), m1.MulNaive(m2)
+		if r := math.Compare(got, want, math.CompareOptions{Rel: 1e-4}); !r.Equal() {
+			t.Fatalf("%v: %v", size, r)
+		}
+	}
+}
+
+func copyInto[T math.Type](dst, src math.Mat[T]) {
+	for i := 0; i < src.Row; i++ {
+		for j := 0; j < src.Col; j++ {
+			dst.Set(i, j, src.Get(i, j))
+		}
+	}
+}
+
+func TestMulBatched(t *testing.T) {
+	as := make([]math.Mat[float32], 100)
+	bs := make([]math.Mat[float32], 100)
+	for i := range as {
+		as[i] = math.NewRandMat[float32](4, 3, math.WithSeed(int64(2*i)))
+		bs[i] = math.NewRandMat[float32](3, 5, math.WithSeed(int64(2*i+1)))
+	}
+
+	for _, tt := range []struct {
+		as, bs []math.Mat[float32]
+	}{
+		{as, bs},
+		{as[:1], bs},
+		{as, bs[:1]},
+		// Matrices of different shapes are multiplied as by math.MulBatched.
+		{[]math.Mat[float32]{as[0], math.NewRandMat[float32](2, 3, math.WithSeed(26))}, bs[:2]},
+	} {
+		outs := gpu.MulBatched(tt.as, tt.bs)
+		for i, out := range outs {
+			want := tt.as[i%len(tt.as)].MulNaive(tt.bs[i%len(tt.bs)])
+			if !out.Eq(want) {
+				t.Fatalf("#%d: GPU receives different results compare to CPU: GPU(%v) vs. CPU(%v)", i, out, want)
+			}
+		}
+
+		// The results do not overlap.
+		next := outs[1].Clone()
+		_ = append(outs[0].Data, -1)
+		if !outs[1].Eq(next) {
+			t.Fatalf("append to a result overwrites the next")
+		}
+	}
+
+	t1 := math.TensorFromMat(math.NewRandMat[float32](6*5, 4, math.WithSeed(24))).Reshape(6, 5, 4)
+	t2 := math.TensorFromMat(math.NewRandMat[float32](6*3, 4, math.WithSeed(25))).Reshape(6, 3, 4).Permute(0, 2, 1)
+	got := gpu.TensorMatMul(t1, t2).Reshape(-1, 3).Mat()
+	want := t1.MatMul(t2).Reshape(-1, 3).Mat()
+	if !got.Eq(want) {
+		t.Fatalf("GPU receives different results compare to CPU: GPU(%v) vs. CPU(%v)", got, want)
+	}
+}
+
+func BenchmarkMulBatched(b *testing.B) {
+	for size := 1 << 1; size <= 1<<4; size *= 2 {
+		as := make([]math.Mat[float32], 1000)
+		bs := make([]math.Mat[float32], 1000)
+		for i := range as {
+			as[i] = math.NewRandMat[float32](size, size, math.WithSeed(int64(2*i)))
+			bs[i] = math.NewRandMat[float32](size, size, math.WithSeed(int64(2*i+1)))
+		}
+
+		b.Run(fmt.Sprintf("GPU(1000x%vx%v)", size, size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				gpu.MulBatched(as, bs)
+			}
+		})
+		b.Run(fmt.Sprintf("CPU(1000x%vx%v)", size, size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				math.MulBatched(as, bs)
+			}
+		})
+	}
+}
+
+func TestOps(t *testing.T) {
+	m1 := math.NewRandMat[float32](13, 7, math.WithSeed(26))
+	m2 := math.NewRandMat[float32](13, 1, math.WithSeed(27))
+	m3 := math.NewRandMat[float32](9, 11, math.WithSeed(28)).Slice(2, 3, 1, 8)
+
+	tests := []struct {
+		gpu, cpu math.Mat[float32]
+	}{
+		{gpu.Add(m1, m2), m1.Add(m2)},
+		{gpu.Sub(m1, m3), m1.Sub(m3)},
+		{gpu.Hadamard(m2, m3), m2.Hadamard(m3)},
+		{gpu.Scale(m1, 3), m1.Scale(3)},
+		{gpu.SumAxis(m1, 0), m1.SumAxis(0)},
+		{gpu.MeanAxis(m1, 1), m1.MeanAxis(1)},
+		{gpu.MaxAxis(m1, 0), m1.MaxAxis(0)},
+		{gpu.MinAxis(m1, 1), m1.MinAxis(1)},
+	}
+	for i, tt := range tests {
+		if !tt.gpu.Eq(tt.cpu) {
+			t.Fatalf("#%d: GPU receives different results compare to CPU: GPU(%v) vs. CPU(%v)", i, tt.gpu, tt.cpu)
+		}
+	}
+
+	i1, j1 := gpu.ArgMax(m1)
+	i2, j2 := m1.ArgMax()
+	if i1 != i2 || j1 != j2 || gpu.Max(m1) != m1.Max() || gpu.Min(m1) != m1.Min() {
+		t.Fatalf("GPU receives different extrema compare to CPU")
+	}
+}
+
+func TestTensorOps(t *testing.T) {
+	t1 := math.TensorFromMat(math.NewRandMat[float32](6, 20, math.WithSeed(29))).Reshape(2, 3, 4, 5)
+	t2 := math.TensorFromMat(math.NewRandMat[float32](4, 1, math.WithSeed(30)))
+	t3 := math.TensorFromMat(math.NewRandMat[float32](5, 4, math.WithSeed(31))).Permute(1, 0)
+
+	tests := []struct {
+		gpu, cpu math.Tensor[float32]
+	}{
+		{gpu.TensorAdd(t1, t2), t1.Add(t2)},
+		{gpu.TensorSub(t1, t3), t1.Sub(t3)},
+		{gpu.TensorHadamard(t2, t3), t2.Hadamard(t3)},
+		{gpu.TensorScale(t1, 3), t1.Scale(3)},
+	}
+	for i, tt := range tests {
+		got := tt.gpu.Reshape(-1, 1).Mat()
+		want := tt.cpu.Reshape(-1, 1).Mat()
+		if !got.Eq(want) {
+			t.Fatalf("#%d: GPU receives different results compare to CPU: GPU(%v) vs. CPU(%v)", i, got, want)
 		}
 	}
 }
@@ -206,6 +753,33 @@ func TestImageEnhance(t *testing.T) {
 	t.Log("diffSum:", diffSum)
 }
 
+func TestFitColorMatrix(t *testing.T) {
+	want := enhance.ColorMatrix{
+		{0.9, 0.1, 0.0, 0.02},
+		{0.05, 0.8, 0.1, -0.01},
+		{0.0, 0.2, 0.7, 0.05},
+	}
+
+	var src, dst []enhance.Color
+	for i := 0; i < 24; i++ {
+		c := enhance.Color{R: float32(i%3) / 2, G: float32(i%4) / 3, B: float32(i%5) / 4}
+		src = append(src, c)
+		dst = append(dst, want.Apply(c))
+	}
+
+	got, err := enhance.FitColorMatrix(src, dst)
+	if err != nil {
+		t.Fatalf("FitColorMatrix: %v", err)
+	}
+	for i := range got {
+		for j := range got[i] {
+			if d := got[i][j] - want[i][j]; d > 1e-4 || d < -1e-4 {
+				t.Fatalf("FitColorMatrix: got %v, want %v", got, want)
+			}
+		}
+	}
+}
+
 func BenchmarkImageEnhance(b *testing.B) {
 	f, err := os.Open("testdata/1.jpg")
 	if err != nil {
@@ -0,0 +1,588 @@
+// Copyright 2023 Changkun Ou <changkun.de>. All rights reserved.
+// Use of this source code is governed by a MIT license that
+// can be found in the LICENSE file.
+
+package main_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	stdmath "math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"changkun.de/x/gogpu/math"
+)
+
+func TestNPY(t *testing.T) {
+	testNPY(t, math.NewRandMat[int8](3, 4, math.IntRange(-100, 100), math.WithSeed(1)))
+	testNPY(t, math.NewRandMat[uint8](3, 4, math.IntRange(0, 256), math.WithSeed(2)))
+	testNPY(t, math.NewRandMat[int32](5, 2, math.IntRange(-1e9, 1e9), math.WithSeed(3)))
+	testNPY(t, math.NewRandMat[uint32](1, 7, math.IntRange(0, 1<<32), math.WithSeed(4)))
+	testNPY(t, math.NewRandMat[int64](4, 4, math.IntRange(-1<<40, 1<<40), math.WithSeed(5)))
+	testNPY(t, math.FromFloat32[math.Float16](math.NewRandMat[float32](3, 3, math.WithSeed(6))))
+	testNPY(t, math.NewRandMat[float32](6, 5, math.WithSeed(7)).Slice(1, 4, 1, 5))
+	testNPY(t, math.NewRandMat[float64](2, 9, math.Normal(0, 1e10), math.WithSeed(8)))
+	testNPY(t, math.NewRandMat[complex64](3, 2, math.WithSeed(9)))
+	testNPY(t, math.NewRandMat[complex128](2, 3, math.WithSeed(10)))
+	testNPY(t, math.Zeros[float32](0, 3))
+
+	if err := math.WriteNPY(&bytes.Buffer{}, math.Zeros[math.BFloat16](1, 1)); !errors.Is(err, math.ErrNPY) {
+		t.Fatalf("BFloat16: want ErrNPY, got %v", err)
+	}
+	var buf bytes.Buffer
+	math.WriteNPY(&buf, math.Zeros[float32](2, 2))
+	if _, err := math.ReadNPY[float64](&buf); !errors.Is(err, math.ErrNPY) {
+		t.Fatalf("mismatched dtype: want ErrNPY, got %v", err)
+	}
+
+	// A shape whose size overflows, or that exceeds the data, is not
+	// allocated up front.
+	for _, shape := range []string{
+		"(4294967296, 4294967296)",
+		"(3037000500, 3037000500)",
+		"(9223372036854775807,)",
+		"(1000000000, 1000)",
+	} {
+		f := npyFile("{'descr': '<f4', 'fortran_order': False, 'shape': "+shape+", }", make([]byte, 16))
+		if _, err := math.ReadNPY[float32](bytes.NewReader(f)); !errors.Is(err, math.ErrNPY) {
+			t.Fatalf("shape %s: want ErrNPY, got %v", shape, err)
+		}
+	}
+
+	// The header length of version 2.0 is not allocated beyond a bound.
+	f := []byte("\x93NUMPY\x02\x00\xff\xff\xff\xff")
+	if _, err := math.ReadNPY[float32](bytes.NewReader(f)); !errors.Is(err, math.ErrNPY) || !strings.Contains(err.Error(), "exceeds") {
+		t.Fatalf("header length: want ErrNPY, got %v", err)
+	}
+}
+
+func testNPY[T math.Elem](t *testing.T, m math.Mat[T]) {
+	t.Helper()
+
+	for _, opts := range [][]math.NPYOption{
+		nil,
+		{math.NPYFortranOrder()},
+		{math.NPYByteOrder(binary.BigEndian)},
+		{math.NPYFortranOrder(), math.NPYByteOrder(binary.BigEndian)},
+		{math.NPYByteOrder(binary.NativeEndian)},
+	} {
+		var buf bytes.Buffer
+		if err := math.WriteNPY(&buf, m, opts...); err != nil {
+			t.Fatalf("%T: %v", m.Data, err)
+		}
+		if len(buf.Bytes())-m.Row*m.Col*math.TypeSize[T]() != 128 {
+			t.Fatalf("%T: the data is not aligned to 64 bytes", m.Data)
+		}
+		got, err := math.ReadNPY[T](&buf)
+		if err != nil {
+			t.Fatalf("%T: %v", m.Data, err)
+		}
+		if r := math.Compare(got, m, math.CompareOptions{}); !r.Equal() || buf.Len() != 0 {
+			t.Fatalf("%T: inconsistent round trip: %v", m.Data, r)
+		}
+	}
+}
+
+// npyFile returns a .npy file with the given header in the format that
+// is written by numpy.save.
+func npyFile(header string, data []byte) []byte {
+	header += strings.Repeat(" ", 63-(10+len(header))%64) + "\n"
+	b := []byte("\x93NUMPY\x01\x00")
+	b = binary.LittleEndian.AppendUint16(b, uint16(len(header)))
+	return append(append(b, header...), data...)
+}
+
+func TestNPYNumPy(t *testing.T) {
+	// np.arange(6, dtype='>i4').reshape(2, 3, order='F')
+	f := npyFile("{'descr': '>i4', 'fortran_order': True, 'shape': (2, 3), }",
+		[]byte{0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 2, 0, 0, 0, 3, 0, 0, 0, 4, 0, 0, 0, 5})
+	m, err := math.ReadNPY[int32](bytes.NewReader(f))
+	want := math.Mat[int32]{Row: 2, Col: 3, Data: []int32{0, 2, 4, 1, 3, 5}}
+	if err != nil || !m.Eq(want) {
+		t.Fatalf("got %v, %v, want %v", m, err, want)
+	}
+
+	// np.array([1.5, -2], dtype='<f8') is a row vector, and
+	// np.float32(3) is a scalar.
+	f = npyFile("{'descr': '<f8', 'fortran_order': False, 'shape': (2,), }",
+		[]byte{0, 0, 0, 0, 0, 0, 0xf8, 0x3f, 0, 0, 0, 0, 0, 0, 0, 0xc0})
+	v, err := math.ReadNPY[float64](bytes.NewReader(f))
+	if err != nil || !v.Eq(math.Mat[float64]{Row: 1, Col: 2, Data: []float64{1.5, -2}}) {
+		t.Fatalf("got %v, %v", v, err)
+	}
+	f = npyFile("{'descr': '<f4', 'fortran_order': False, 'shape': (), }", []byte{0, 0, 0x40, 0x40})
+	s, err := math.ReadNPY[float32](bytes.NewReader(f))
+	if err != nil || s.Row != 1 || s.Col != 1 || s.Data[0] != 3 {
+		t.Fatalf("got %v, %v", s, err)
+	}
+
+	for _, f := range [][]byte{
+		[]byte("not a npy file"),
+		npyFile("{'descr': '<f4', 'fortran_order': False, 'shape': (2, 2, 2), }", make([]byte, 32)),
+		npyFile("{'descr': '<f4', 'fortran_order': False, 'shape': (2, 2), }", make([]byte, 15)),
+		npyFile("{'descr': '<f4', 'shape': (2, 2), }", make([]byte, 16)),
+	} {
+		if _, err := math.ReadNPY[float32](bytes.NewReader(f)); !errors.Is(err, math.ErrNPY) {
+			t.Fatalf("want ErrNPY, got %v", err)
+		}
+	}
+}
+
+func TestNPZ(t *testing.T) {
+	ms := map[string]math.Mat[float32]{
+		"weight": math.NewRandMat[float32](4, 3, math.WithSeed(11)),
+		"bias":   math.NewRandMat[float32](1, 3, math.WithSeed(12)),
+	}
+	var buf bytes.Buffer
+	if err := math.WriteNPZ(&buf, ms); err != nil {
+		t.Fatal(err)
+	}
+	got, err := math.ReadNPZ[float32](bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil || len(got) != len(ms) {
+		t.Fatalf("got %v, %v", got, err)
+	}
+	for name, m := range ms {
+		if !got[name].Eq(m) {
+			t.Fatalf("%s: got %v, want %v", name, got[name], m)
+		}
+	}
+
+	// numpy.savez_compressed deflates the arrays.
+	buf.Reset()
+	z := zip.NewWriter(&buf)
+	w, _ := z.CreateHeader(&zip.FileHeader{Name: "x.npy", Method: zip.Deflate})
+	math.WriteNPY(w, ms["weight"], math.NPYFortranOrder())
+	z.Close()
+	got, err = math.ReadNPZ[float32](bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil || !got["x"].Eq(ms["weight"]) {
+		t.Fatalf("got %v, %v", got, err)
+	}
+}
+
+func TestMTX(t *testing.T) {
+	m := math.NewRandMat[float64](5, 4, math.Normal(0, 1e5), math.WithSeed(13))
+	var buf bytes.Buffer
+	if err := math.WriteMTX(&buf, m.Slice(1, 5, 0, 3)); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := math.ReadMTX[float64](&buf); err != nil || !got.Eq(m.Slice(1, 5, 0, 3)) {
+		t.Fatalf("array: got %v, %v", got, err)
+	}
+
+	c := math.NewRandMat[complex64](3, 6, math.WithSeed(14))
+	c.Data[4] = 0
+	buf.Reset()
+	if err := math.WriteMTXCSR(&buf, math.CSRFromMat(c)); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := math.ReadMTXCSR[complex64](bytes.NewReader(buf.Bytes())); err != nil || !got.Mat().Eq(c) || got.NNZ() != 17 {
+		t.Fatalf("coordinate: got %v, %v", got, err)
+	}
+	if got, err := math.ReadMTX[complex64](&buf); err != nil || !got.Eq(c) {
+		t.Fatalf("coordinate: got %v, %v", got, err)
+	}
+
+	for _, tt := range []struct {
+		file string
+		want math.Mat[float32]
+	}{
+		{`%%MatrixMarket matrix coordinate real symmetric
+% a comment
+
+3 3 4
+1 1 1.5
+2 1 2
+3 2 -3
+3 3 4
+`, math.Mat[float32]{Row: 3, Col: 3, Data: []float32{1.5, 2, 0, 2, 0, -3, 0, -3, 4}}},
+		{`%%MatrixMarket matrix array real skew-symmetric
+3 3
+1
+2
+3
+`, math.Mat[float32]{Row: 3, Col: 3, Data: []float32{0, -1, -2, 1, 0, -3, 2, 3, 0}}},
+		{`%%MatrixMarket matrix array integer symmetric
+2 2
+1
+2
+3
+`, math.Mat[float32]{Row: 2, Col: 2, Data: []float32{1, 2, 2, 3}}},
+		{`%%MatrixMarket matrix coordinate pattern general
+2 3 2
+1 3
+2 1
+`, math.Mat[float32]{Row: 2, Col: 3, Data: []float32{0, 0, 1, 1, 0, 0}}},
+	} {
+		got, err := math.ReadMTX[float32](strings.NewReader(tt.file))
+		if err != nil || !got.Eq(tt.want) {
+			t.Fatalf("got %v, %v, want %v", got, err, tt.want)
+		}
+	}
+
+	h, err := math.ReadMTX[complex128](strings.NewReader(`%%MatrixMarket matrix coordinate complex hermitian
+2 2 2
+1 1 1 0
+2 1 2 3
+`))
+	if want := (math.Mat[complex128]{Row: 2, Col: 2, Data: []complex128{1, 2 - 3i, 2 + 3i, 0}}); err != nil || !h.Eq(want) {
+		t.Fatalf("hermitian: got %v, %v, want %v", h, err, want)
+	}
+}
+
+func TestMTXErrors(t *testing.T) {
+	for _, tt := range []struct {
+		file string
+		line int
+	}{
+		{``, 1},
+		{`%%MatrixMarket matrix coordinate real`, 1},
+		{`%%MatrixMarket matrix coordinate real unknown`, 1},
+		{`%%MatrixMarket matrix array pattern general`, 1},
+		{`%%MatrixMarket matrix coordinate complex general`, 1},
+		{"%%MatrixMarket matrix coordinate real general\n%\n2 x 1\n", 3},
+		{"%%MatrixMarket matrix coordinate real general\n2 2 1\n3 1 1\n", 3},
+		{"%%MatrixMarket matrix coordinate real general\n2 2 1\n1 1 1 1\n", 3},
+		{"%%MatrixMarket matrix coordinate real general\n2 2 1\n1 1 x\n", 3},
+		{"%%MatrixMarket matrix coordinate real symmetric\n2 2 1\n1 2 1\n", 3},
+		{"%%MatrixMarket matrix coordinate real symmetric\n2 3 1\n1 1 1\n", 2},
+		{"%%MatrixMarket matrix coordinate real general\n2 2 2\n1 1 1\n", 4},
+		{"%%MatrixMarket matrix array real general\n1 1\n1\n2\n", 4},
+
+		// A corrupt size is rejected before the elements are allocated.
+		{"%%MatrixMarket matrix coordinate real general\n2 2 -1\n", 2},
+		{"%%MatrixMarket matrix coordinate real general\n4294967296 4294967296 1\n1 1 1\n", 2},
+		{"%%MatrixMarket matrix coordinate real general\n100000 100000 1\n1 1 1\n", 2},
+		{"%%MatrixMarket matrix array real general\n3037000500 3037000500\n1\n", 2},
+		{"%%MatrixMarket matrix array real symmetric\n3037000499 3037000499\n1\n", 2},
+		{"%%MatrixMarket matrix coordinate real general\n2 2 2147483647\n1 1 1\n", 4},
+	} {
+		_, err := math.ReadMTX[float32](strings.NewReader(tt.file))
+		var pe *math.ParseError
+		if !errors.As(err, &pe) || pe.Line != tt.line {
+			t.Fatalf("%q: want a parse error at line %d, got %v", tt.file, tt.line, err)
+		}
+	}
+
+	for _, tt := range []struct {
+		file string
+		line int
+	}{
+		{"%%MatrixMarket matrix coordinate real general\n4611686018427387904 1 0\n", 2},
+		{"%%MatrixMarket matrix coordinate real general\n100000 100000 2147483647\n1 1 1\n", 4},
+		{"%%MatrixMarket matrix array real general\n3037000500 3037000500\n1\n", 2},
+		{"%%MatrixMarket matrix array real general\n1000000 1000\n1\n", 4},
+	} {
+		_, err := math.ReadMTXCSR[float32](strings.NewReader(tt.file))
+		var pe *math.ParseError
+		if !errors.As(err, &pe) || pe.Line != tt.line {
+			t.Fatalf("CSR %q: want a parse error at line %d, got %v", tt.file, tt.line, err)
+		}
+	}
+
+	// A large sparse matrix is read by ReadMTXCSR.
+	large := "%%MatrixMarket matrix coordinate real general\n20000 20000 1\n1 1 1\n"
+	if _, err := math.ReadMTX[float32](strings.NewReader(large)); err == nil || !strings.Contains(err.Error(), "use ReadMTXCSR") {
+		t.Fatalf("large dense: want an error that points to ReadMTXCSR, got %v", err)
+	}
+	if s, err := math.ReadMTXCSR[float32](strings.NewReader(large)); err != nil || s.Row != 20000 || s.NNZ() != 1 {
+		t.Fatalf("large sparse: got %dx%d with %d entries, %v", s.Row, s.Col, s.NNZ(), err)
+	}
+
+	_, err := math.ReadMTX[int32](strings.NewReader("%%MatrixMarket matrix array real general\n1 1\n1\n"))
+	if !errors.As(err, new(*math.ParseError)) {
+		t.Fatalf("real field as int32: want a parse error, got %v", err)
+	}
+}
+
+func TestCSV(t *testing.T) {
+	testCSV(t, math.NewRandMat[float32](4, 3, math.WithSeed(15)))
+	testCSV(t, math.NewRandMat[int64](2, 5, math.IntRange(-1<<40, 1<<40), math.WithSeed(16)))
+	testCSV(t, math.NewRandMat[complex128](3, 3, math.WithSeed(17)))
+
+	m, err := math.ReadCSV[float64](strings.NewReader("1, 2.5,3\n-4,5e3,6\n"))
+	if want := (math.Mat[float64]{Row: 2, Col: 3, Data: []float64{1, 2.5, 3, -4, 5000, 6}}); err != nil || !m.Eq(want) {
+		t.Fatalf("got %v, %v, want %v", m, err, want)
+	}
+	for _, tt := range []struct {
+		file string
+		line int
+	}{
+		{"1,2\n3,4,5\n", 2},
+		{"1,2\n3,4\n\n5,x\n", 4},
+		{"1,2\n\"3,4\n", 2},
+	} {
+		_, err := math.ReadCSV[float64](strings.NewReader(tt.file))
+		var pe *math.ParseError
+		if !errors.As(err, &pe) || pe.Line != tt.line {
+			t.Fatalf("%q: want a parse error at line %d, got %v", tt.file, tt.line, err)
+		}
+	}
+}
+
+func testCSV[T math.Elem](t *testing.T, m math.Mat[T]) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := math.WriteCSV(&buf, m); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := math.ReadCSV[T](&buf); err != nil || !got.Eq(m) {
+		t.Fatalf("%T: got %v, %v, want %v", m.Data, got, err, m)
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	testMarshal(t, math.NewRandMat[int8](3, 4, math.IntRange(-100, 100), math.WithSeed(18)))
+	testMarshal(t, math.NewRandMat[uint8](3, 4, math.IntRange(0, 256), math.WithSeed(19)))
+	testMarshal(t, math.NewRandMat[int32](5, 2, math.IntRange(-1e9, 1e9), math.WithSeed(20)))
+	testMarshal(t, math.NewRandMat[uint32](1, 7, math.IntRange(0, 1<<32), math.WithSeed(21)))
+	testMarshal(t, math.NewRandMat[int64](4, 4, math.IntRange(-1<<60, 1<<60), math.WithSeed(22)))
+	testMarshal(t, math.FromFloat32[math.Float16](math.NewRandMat[float32](3, 3, math.WithSeed(23))))
+	testMarshal(t, math.FromFloat32[math.BFloat16](math.NewRandMat[float32](3, 3, math.WithSeed(24))))
+	testMarshal(t, math.NewRandMat[float32](6, 5, math.WithSeed(25)).Slice(1, 4, 1, 5))
+	testMarshal(t, math.NewRandMat[float64](2, 9, math.Normal(0, 1e300), math.WithSeed(26)))
+	testMarshal(t, math.NewRandMat[complex64](3, 2, math.WithSeed(27)))
+	testMarshal(t, math.NewRandMat[complex128](2, 3, math.WithSeed(28)))
+	testMarshal(t, math.Zeros[float32](0, 3))
+	testMarshal(t, math.Mat[float64]{Row: 1, Col: 3, Data: []float64{stdmath.NaN(), stdmath.Inf(1), stdmath.Inf(-1)}})
+
+	// uint8 must not become base64.
+	b, err := json.Marshal(math.Mat[uint8]{Row: 1, Col: 3, Data: []uint8{0, 128, 255}})
+	if want := `{"row":1,"col":3,"data":[0,128,255]}`; err != nil || string(b) != want {
+		t.Fatalf("got %s, %v, want %s", b, err, want)
+	}
+	b, err = json.Marshal(math.Mat[complex64]{Row: 1, Col: 1, Data: []complex64{1.5 - 2i}})
+	if want := `{"row":1,"col":1,"data":[[1.5,-2]]}`; err != nil || string(b) != want {
+		t.Fatalf("got %s, %v, want %s", b, err, want)
+	}
+
+	// Gob uses the binary encoding within other values.
+	type model struct {
+		Name   string
+		Weight math.Mat[float32]
+	}
+	in := model{"dense", math.NewRandMat[float32](4, 3, math.WithSeed(29))}
+	var buf bytes.Buffer
+	var out model
+	if err := gob.NewEncoder(&buf).Encode(in); err != nil {
+		t.Fatal(err)
+	}
+	if err := gob.NewDecoder(&buf).Decode(&out); err != nil || out.Name != in.Name || !out.Weight.Eq(in.Weight) {
+		t.Fatalf("gob: got %v, %v, want %v", out, err, in)
+	}
+}
+
+func testMarshal[T math.Elem](t *testing.T, m math.Mat[T]) {
+	t.Helper()
+
+	// The elements must be identical, including NaN.
+	eq := func(a, b math.Mat[T]) bool {
+		return a.Row == b.Row && a.Col == b.Col && fmt.Sprint(a.Clone().Data) == fmt.Sprint(b.Clone().Data)
+	}
+
+	b, err := m.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) != 32+m.Row*m.Col*math.TypeSize[T]() {
+		t.Fatalf("%T: got %d bytes", m.Data, len(b))
+	}
+	var got math.Mat[T]
+	if err := got.UnmarshalBinary(b); err != nil || !eq(got, m) {
+		t.Fatalf("%T binary: got %v, %v, want %v", m.Data, got, err, m)
+	}
+
+	b, err = json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = math.Mat[T]{}
+	if err := json.Unmarshal(b, &got); err != nil || !eq(got, m) {
+		t.Fatalf("%T json %s: got %v, %v, want %v", m.Data, b, got, err, m)
+	}
+}
+
+func TestMarshalBinaryErrors(t *testing.T) {
+	m := math.Mat[int32]{Row: 2, Col: 2, Data: []int32{1, -2, 3, 1 << 20}}
+	b, _ := m.MarshalBinary()
+
+	// The elements of the other byte order are swapped.
+	swapped := append([]byte(nil), b...)
+	if swapped[6] == '<' {
+		swapped[6] = '>'
+	} else {
+		swapped[6] = '<'
+	}
+	for i := 32; i < len(swapped); i += 4 {
+		swapped[i], swapped[i+1], swapped[i+2], swapped[i+3] = swapped[i+3], swapped[i+2], swapped[i+1], swapped[i]
+	}
+	var got math.Mat[int32]
+	if err := got.UnmarshalBinary(swapped); err != nil || !got.Eq(m) {
+		t.Fatalf("swapped: got %v, %v, want %v", got, err, m)
+	}
+
+	modify := func(f func(b []byte) []byte) []byte {
+		return f(append([]byte(nil), b...))
+	}
+	for name, in := range map[string][]byte{
+		"empty":     nil,
+		"magic":     modify(func(b []byte) []byte { b[0] = 'X'; return b }),
+		"version":   modify(func(b []byte) []byte { b[4] = 2; return b }),
+		"order":     modify(func(b []byte) []byte { b[6] = '|'; return b }),
+		"reserved":  modify(func(b []byte) []byte { b[30] = 1; return b }),
+		"truncated": b[:len(b)-1],
+		"trailing":  append(append([]byte(nil), b...), 0),
+		"shape": modify(func(b []byte) []byte {
+			binary.LittleEndian.PutUint64(b[8:], 1<<62)
+			binary.LittleEndian.PutUint64(b[16:], 4)
+			return b
+		}),
+	} {
+		if err := got.UnmarshalBinary(in); !errors.Is(err, math.ErrEncoding) {
+			t.Fatalf("%s: want ErrEncoding, got %v", name, err)
+		}
+	}
+
+	var f math.Mat[float32]
+	if err := f.UnmarshalBinary(b); !errors.Is(err, math.ErrEncoding) {
+		t.Fatalf("int32 as float32: want ErrEncoding, got %v", err)
+	}
+}
+
+func TestMarshalJSONErrors(t *testing.T) {
+	for _, in := range []string{
+		`{"row":2,"col":2,"data":[1,2,3]}`,
+		`{"row":2,"col":2,"data":[1,2,3,4,5]}`,
+		`{"row":0,"col":2,"data":[1]}`,
+		`{"row":-1,"col":-2,"data":[2]}`,
+		`{"row":3037000500,"col":3037000500,"data":[]}`,
+		`{"col":1,"data":[1]}`,
+		`{"row":1,"col":1}`,
+		`{"row":1,"col":1,"data":[1],"stride":1}`,
+		`{"row":1,"col":1,"data":"AQ=="}`,
+		`{"row":1,"col":1,"data":[null]}`,
+		`{"row":1,"col":1,"data":["1"]}`,
+		`{"row":1,"col":1,"data":[256]}`,
+		`{"row":1,"col":1,"data":[1.5]}`,
+	} {
+		var m math.Mat[uint8]
+		if err := json.Unmarshal([]byte(in), &m); !errors.Is(err, math.ErrEncoding) {
+			t.Fatalf("%s: want ErrEncoding, got %v", in, err)
+		}
+	}
+	for _, in := range []string{
+		`{"row":1,"col":1,"data":[1]}`,
+		`{"row":1,"col":1,"data":[[1]]}`,
+		`{"row":1,"col":1,"data":[[1,2,3]]}`,
+	} {
+		var m math.Mat[complex128]
+		if err := json.Unmarshal([]byte(in), &m); !errors.Is(err, math.ErrEncoding) {
+			t.Fatalf("%s: want ErrEncoding, got %v", in, err)
+		}
+	}
+}
+
+func TestMapped(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("memory mapping is only supported on Linux")
+	}
+
+	path := filepath.Join(t.TempDir(), "m.mat")
+	for _, shape := range [][2]int{{-1, 2}, {2, -1}, {stdmath.MaxInt, 2}, {stdmath.MaxInt / 8, 1}} {
+		if _, err := math.CreateMapped[float64](path, shape[0], shape[1]); !errors.Is(err, math.ErrEncoding) {
+			t.Fatalf("shape %v: want ErrEncoding, got %v", shape, err)
+		}
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Fatalf("shape %v: file is created", shape)
+		}
+	}
+
+	want := math.NewRandMat[float64](30, 17, math.WithSeed(30))
+	w, err := math.CreateMapped[float64](path, want.Row, want.Col)
+	if err != nil {
+		t.Fatal(err)
+	}
+	copy(w.Data, want.Data)
+	if err := w.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The file is in the binary encoding.
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got math.Mat[float64]
+	if err := got.UnmarshalBinary(b); err != nil || !got.Eq(want) {
+		t.Fatalf("file: got %v, %v", got, err)
+	}
+
+	r, err := math.OpenMapped[float64](path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Eq(want) {
+		t.Fatalf("read-only: got %v, want %v", r.Mat, want)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	rw, err := math.OpenMapped[float64](path, math.MapWritable())
+	if err != nil {
+		t.Fatal(err)
+	}
+	rw.Set(3, 4, -1)
+	want.Set(3, 4, -1)
+	if err := rw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	b, _ = os.ReadFile(path)
+	if err := got.UnmarshalBinary(b); err != nil || !got.Eq(want) {
+		t.Fatalf("read-write: got %v, %v", got, err)
+	}
+
+	// The product of mapped matrices is written to a mapped matrix.
+	r, err = math.OpenMapped[float64](path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := math.NewRandMat[float64](17, 5, math.WithSeed(31))
+	p, err := math.CreateMapped[float64](filepath.Join(t.TempDir(), "p.mat"), 30, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	math.MulTiled(p.Mat, r.Mat, n, 8, nil)
+	if !p.Eq(want.MulNaive(n)) {
+		t.Fatalf("MulTiled: got %v, want %v", p.Mat, want.MulNaive(n))
+	}
+	r.Close()
+	p.Close()
+
+	if _, err := math.OpenMapped[float32](path); !errors.Is(err, math.ErrEncoding) {
+		t.Fatalf("float64 as float32: want ErrEncoding, got %v", err)
+	}
+	os.WriteFile(path, b[:len(b)-1], 0o644)
+	if _, err := math.OpenMapped[float64](path); !errors.Is(err, math.ErrEncoding) {
+		t.Fatalf("truncated: want ErrEncoding, got %v", err)
+	}
+	os.WriteFile(path, b[:16], 0o644)
+	if _, err := math.OpenMapped[float64](path); !errors.Is(err, math.ErrEncoding) {
+		t.Fatalf("header: want ErrEncoding, got %v", err)
+	}
+	if _, err := math.OpenMapped[float64](filepath.Join(t.TempDir(), "missing")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("missing: want ErrNotExist, got %v", err)
+	}
+}
@@ -0,0 +1,254 @@
+// Copyright 2023 Changkun Ou <changkun.de>. All rights reserved.
+// Use of this source code is governed by a MIT license that
+// can be found in the LICENSE file.
+
+package main_test
+
+import (
+	"errors"
+	stdmath "math"
+	"testing"
+
+	"changkun.de/x/gogpu/gpu"
+	"changkun.de/x/gogpu/math"
+)
+
+// approxEq is math.Mat[T].Eq with a given absolute tolerance.
+func approxEq[T math.Float](m, n math.Mat[T], tol float64) bool {
+	if m.Row != n.Row || m.Col != n.Col {
+		return false
+	}
+	for i := 0; i < m.Row; i++ {
+		for j := 0; j < m.Col; j++ {
+			if stdmath.Abs(float64(m.Get(i, j))-float64(n.Get(i, j))) > tol {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func TestLU(t *testing.T) {
+	for _, n := range []int{1, 2, 5, 16} {
+		// A diagonally dominant matrix with reversed rows is well
+		// conditioned but requires pivoting.
+		d := math.NewRandMat[float32](n, n, math.WithSeed(1)).Add(math.Identity[float32](n).Scale(float32(n)))
+		a := math.Mat[float32]{Row: n, Col: n, Data: make([]float32, n*n)}
+		for i := 0; i < n; i++ {
+			for j := 0; j < n; j++ {
+				a.Set(i, j, d.Get(n-1-i, j))
+			}
+		}
+
+		inv, err := math.Inverse(a)
+		if err != nil {
+			t.Fatalf("Inverse(%dx%d): %v", n, n, err)
+		}
+		if id := a.Mul(inv); !approxEq(id, math.Identity[float32](n), 1e-4) {
+			t.Fatalf("A*Inverse(A) is not identity: %v", id)
+		}
+
+		b := math.NewRandMat[float32](n, 3, math.WithSeed(2))
+		x, err := math.Solve(a, b)
+		if err != nil {
+			t.Fatalf("Solve(%dx%d): %v", n, n, err)
+		}
+		if ax := a.MulNaive(x); !approxEq(ax, b, 1e-4) {
+			t.Fatalf("A*x is not b: %v vs. %v", ax, b)
+		}
+
+		f := math.FactorLU(a)
+		pa := math.Mat[float32]{Row: n, Col: n, Data: make([]float32, n*n)}
+		for i, p := range f.Pivot() {
+			for j := 0; j < n; j++ {
+				pa.Set(i, j, a.Get(p, j))
+			}
+		}
+		if lu := f.L().MulNaive(f.U()); !approxEq(lu, pa, 1e-5) {
+			t.Fatalf("L*U is not P*A: %v vs. %v", lu, pa)
+		}
+	}
+
+	a := math.Mat[float32]{
+		Row: 3, Col: 3,
+		Data: []float32{
+			2, 0, 1,
+			1, 3, 2,
+			1, 1, 2,
+		},
+	}
+	if d := math.Det(a); stdmath.Abs(float64(d)-6) > 1e-5 {
+		t.Fatalf("Det: got %v, want 6", d)
+	}
+
+	singular := math.Mat[float32]{
+		Row: 3, Col: 3,
+		Data: []float32{
+			1, 2, 3,
+			4, 5, 6,
+			7, 8, 9,
+		},
+	}
+	if _, err := math.Inverse(singular); !errors.Is(err, math.ErrSingular) {
+		t.Fatalf("Inverse of a singular matrix: got %v, want %v", err, math.ErrSingular)
+	}
+	if _, err := math.Solve(singular, math.NewRandMat[float32](3, 1, math.WithSeed(3))); !errors.Is(err, math.ErrSingular) {
+		t.Fatalf("Solve of a singular matrix: got %v, want %v", err, math.ErrSingular)
+	}
+}
+
+func TestQR(t *testing.T) {
+	a := math.NewRandMat[float32](20, 5, math.WithSeed(4))
+	f := math.FactorQR(a)
+	if !f.FullRank() {
+		t.Fatalf("random matrix is rank deficient: rank %v", f.Rank())
+	}
+	q, r := f.Q(), f.R()
+	if qr := q.MulNaive(r); !approxEq(qr, a, 1e-5) {
+		t.Fatalf("Q*R is not A: %v vs. %v", qr, a)
+	}
+	if qtq := q.View().T().Mat().MulNaive(q); !approxEq(qtq, math.Identity[float32](5), 1e-5) {
+		t.Fatalf("Q'*Q is not identity: %v", qtq)
+	}
+
+	// A consistent system is solved exactly, and the solution of an
+	// inconsistent one satisfies the normal equations A'*A*x = A'*b.
+	x0 := math.NewRandMat[float32](5, 2, math.WithSeed(5))
+	x, res, err := math.LeastSquares(a, a.Mul(x0))
+	if err != nil {
+		t.Fatalf("LeastSquares: %v", err)
+	}
+	if !approxEq(x, x0, 1e-4) || !approxEq(res, math.Mat[float32]{Row: 1, Col: 2, Data: []float32{0, 0}}, 1e-6) {
+		t.Fatalf("LeastSquares: got %v with residuals %v, want %v", x, res, x0)
+	}
+
+	b := math.NewRandMat[float32](20, 1, math.WithSeed(6))
+	x, res, err = math.LeastSquares(a, b)
+	if err != nil {
+		t.Fatalf("LeastSquares: %v", err)
+	}
+	at := a.View().T().Mat()
+	if lhs, rhs := at.Mul(a).Mul(x), at.Mul(b); !approxEq(lhs, rhs, 1e-4) {
+		t.Fatalf("normal equations are not satisfied: %v vs. %v", lhs, rhs)
+	}
+	if d := a.Mul(x).Sub(b); stdmath.Abs(float64(d.Hadamard(d).Sum()-res.Data[0])) > 1e-4 {
+		t.Fatalf("residuals: got %v, want %v", res.Data[0], d.Hadamard(d).Sum())
+	}
+
+	// Duplicated columns are linearly dependent.
+	dep := math.Mat[float32]{Row: 20, Col: 3, Data: make([]float32, 60)}
+	for i := 0; i < 20; i++ {
+		dep.Set(i, 0, a.Get(i, 0))
+		dep.Set(i, 1, a.Get(i, 1))
+		dep.Set(i, 2, a.Get(i, 0))
+	}
+	if _, _, err := math.LeastSquares(dep, b); !errors.Is(err, math.ErrRankDeficient) {
+		t.Fatalf("LeastSquares of a rank deficient matrix: got %v, want %v", err, math.ErrRankDeficient)
+	}
+}
+
+func TestCholesky(t *testing.T) {
+	// B*B' + n*I is symmetric positive definite.
+	n := 12
+	b := math.NewRandMat[float32](n, n, math.WithSeed(7))
+	a := b.MulNaive(b.View().T().Mat()).Add(math.Identity[float32](n).Scale(float32(n)))
+
+	c, err := math.FactorCholesky(a)
+	if err != nil {
+		t.Fatalf("FactorCholesky: %v", err)
+	}
+	l := c.L()
+	if llt := l.MulNaive(l.View().T().Mat()); !approxEq(llt, a, 1e-4) {
+		t.Fatalf("L*L' is not A: %v vs. %v", llt, a)
+	}
+
+	rhs := math.NewRandMat[float32](n, 2, math.WithSeed(8))
+	x, err := math.SolveSPD(a, rhs)
+	if err != nil {
+		t.Fatalf("SolveSPD: %v", err)
+	}
+	if ax := a.MulNaive(x); !approxEq(ax, rhs, 1e-4) {
+		t.Fatalf("A*x is not b: %v vs. %v", ax, rhs)
+	}
+
+	want := stdmath.Log(float64(math.Det(a)))
+	if got := c.LogDet(); stdmath.Abs(float64(got)-want) > 1e-3 {
+		t.Fatalf("LogDet: got %v, want %v", got, want)
+	}
+
+	for _, m := range []math.Mat[float32]{
+		{Row: 2, Col: 2, Data: []float32{1, 2, 2, 1}}, // indefinite
+		{Row: 2, Col: 2, Data: []float32{2, 1, 0, 2}}, // asymmetric
+	} {
+		if _, err := math.FactorCholesky(m); !errors.Is(err, math.ErrNotPositiveDefinite) {
+			t.Fatalf("FactorCholesky(%v): got %v, want %v", m, err, math.ErrNotPositiveDefinite)
+		}
+	}
+}
+
+func TestEigenSym(t *testing.T) {
+	n := 10
+	b := math.NewRandMat[float32](n, n, math.WithSeed(9))
+	a := b.Add(b.View().T().Mat())
+
+	for _, mul := range []math.MulFunc[float32]{nil, gpu.Mul[float32]} {
+		e, err := math.FactorEigenSym(a, mul)
+		if err != nil {
+			t.Fatalf("FactorEigenSym: %v", err)
+		}
+		values, v := e.Values(), e.Vectors()
+		for i := 1; i < n; i++ {
+			if values[i] > values[i-1] {
+				t.Fatalf("eigenvalues are not sorted: %v", values)
+			}
+		}
+
+		d := math.Mat[float32]{Row: n, Col: n, Data: make([]float32, n*n)}
+		for i, l := range values {
+			d.Set(i, i, l)
+		}
+		if vdvt := v.Mul(d).Mul(v.View().T().Mat()); !approxEq(vdvt, a, 1e-4) {
+			t.Fatalf("V*D*V' is not A: %v vs. %v", vdvt, a)
+		}
+		if vtv := v.View().T().Mat().Mul(v); !approxEq(vtv, math.Identity[float32](n), 1e-4) {
+			t.Fatalf("V'*V is not identity: %v", vtv)
+		}
+	}
+}
+
+func TestSVD(t *testing.T) {
+	for _, a := range []math.Mat[float32]{
+		math.NewRandMat[float32](12, 5, math.WithSeed(10)),
+		math.NewRandMat[float32](4, 9, math.WithSeed(11)),
+	} {
+		for _, mul := range []math.MulFunc[float32]{nil, gpu.Mul[float32]} {
+			f, err := math.FactorSVD(a, mul)
+			if err != nil {
+				t.Fatalf("FactorSVD: %v", err)
+			}
+			u, s, v := f.U(), f.Values(), f.V()
+			for i := 1; i < len(s); i++ {
+				if s[i] > s[i-1] {
+					t.Fatalf("singular values are not sorted: %v", s)
+				}
+			}
+
+			d := math.Mat[float32]{Row: len(s), Col: len(s), Data: make([]float32, len(s)*len(s))}
+			for i, v := range s {
+				d.Set(i, i, v)
+			}
+			if usvt := u.Mul(d).Mul(v.View().T().Mat()); !approxEq(usvt, a, 1e-4) {
+				t.Fatalf("U*S*V' is not A: %v vs. %v", usvt, a)
+			}
+			if utu := u.View().T().Mat().Mul(u); !approxEq(utu, math.Identity[float32](len(s)), 1e-4) {
+				t.Fatalf("U'*U is not identity: %v", utu)
+			}
+			if vtv := v.View().T().Mat().Mul(v); !approxEq(vtv, math.Identity[float32](len(s)), 1e-4) {
+				t.Fatalf("V'*V is not identity: %v", vtv)
+			}
+			if f.Rank() != len(s) {
+				t.Fatalf("Rank: got %v, want %v", f.Rank(), len(s))
+			}
+		}
+	}
+}
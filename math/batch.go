@@ -0,0 +1,59 @@
+// Copyright 2023 Changkun Ou <changkun.de>. All rights reserved.
+// Use of this source code is governed by a MIT license that
+// can be found in the LICENSE file.
+
+package math
+
+// MulBatched multiplies every pair of matrices as[i] and bs[i] in
+// parallel and returns the results. If one of the two batches consists
+// of a single matrix, the matrix is multiplied with every matrix of the
+// other batch.
+func MulBatched[T Type](as, bs []Mat[T]) []Mat[T] {
+	n := BatchLen(len(as), len(bs))
+	rs := make([]Mat[T], n)
+	parallel(n, func(i int) {
+		rs[i] = as[i%len(as)].Mul(bs[i%len(bs)])
+	})
+	return rs
+}
+
+// BatchLen returns the number of results of a batched operation
+// between two batches of the given lengths. It panics if the batches
+// are neither of the same length nor one of them has a length of 1.
+func BatchLen(n1, n2 int) int {
+	switch {
+	case n1 == n2 || n2 == 1:
+		return n1
+	case n1 == 1:
+		return n2
+	}
+	panic("math: mismatched batch size")
+}
+
+// MatMul returns the batched matrix multiplication of two 3-D tensors
+// of shapes (batch, m, k) and (batch, k, n), which results in a tensor
+// of shape (batch, m, n). A batch dimension of size 1 is broadcast to
+// the batch size of the other tensor. The matrices of a batch are
+// multiplied in parallel.
+func (t Tensor[T]) MatMul(u Tensor[T]) Tensor[T] {
+	if t.Dim() != 3 || u.Dim() != 3 || t.Shape[2] != u.Shape[1] {
+		panic("math: mismatched matrix dimension")
+	}
+
+	n := BatchLen(t.Shape[0], u.Shape[0])
+	r := NewTensor[T](n, t.Shape[1], u.Shape[2])
+	parallel(n, func(i int) {
+		m := t.batch(i % t.Shape[0]).Mul(u.batch(i % u.Shape[0]))
+		copy(r.Data[i*r.Strides[0]:], m.Data)
+	})
+	return r
+}
+
+// batch returns the i-th matrix of a 3-D tensor.
+func (t Tensor[T]) batch(i int) Mat[T] {
+	m := Tensor[T]{Shape: t.Shape[1:], Strides: t.Strides[1:]}
+	if m.Size() > 0 {
+		m.Data = t.Data[i*t.Strides[0]:]
+	}
+	return m.Mat()
+}
@@ -0,0 +1,188 @@
+// Copyright 2023 Changkun Ou <changkun.de>. All rights reserved.
+// Use of this source code is governed by a MIT license that
+// can be found in the LICENSE file.
+
+package math
+
+import "math/bits"
+
+// BitMat represents a boolean matrix that packs 64 elements into
+// a word.
+//
+// Every row occupies Words() words of Data, and the element (i, j) is
+// the (j%64)-th bit of the (j/64)-th word of the i-th row. The unused
+// bits of the last word of a row are always zero.
+type BitMat struct {
+	Row  int
+	Col  int
+	Data []uint64
+}
+
+// NewBitMat returns a boolean matrix whose elements are all false.
+func NewBitMat(row, col int) BitMat {
+	return BitMat{Row: row, Col: col, Data: make([]uint64, row*words(col))}
+}
+
+// BitMatFromMat returns a boolean matrix whose elements are true where
+// the elements of m are non-zero.
+func BitMatFromMat(m Mat[uint8]) BitMat {
+	b := NewBitMat(m.Row, m.Col)
+	parallel(m.Row, func(i int) {
+		row := b.row(i)
+		for j := 0; j < m.Col; j++ {
+			if m.Get(i, j) != 0 {
+				row[j/64] |= 1 << (j % 64)
+			}
+		}
+	})
+	return b
+}
+
+// Mat returns the boolean matrix as a matrix of zeros and ones.
+func (b BitMat) Mat() Mat[uint8] {
+	m := Mat[uint8]{Row: b.Row, Col: b.Col, Data: make([]uint8, b.Row*b.Col)}
+	parallel(b.Row, func(i int) {
+		row := b.row(i)
+		for j := 0; j < b.Col; j++ {
+			m.Data[i*b.Col+j] = uint8(row[j/64] >> (j % 64) & 1)
+		}
+	})
+	return m
+}
+
+// Words returns the number of words of a row.
+func (b BitMat) Words() int { return words(b.Col) }
+
+// Get gets the corresponding element at (i, j)
+func (b BitMat) Get(i, j int) bool {
+	b.check(i, j)
+	return b.row(i)[j/64]>>(j%64)&1 == 1
+}
+
+// Set sets the given value to the matrix at (i, j)
+func (b BitMat) Set(i, j int, v bool) {
+	b.check(i, j)
+	if v {
+		b.row(i)[j/64] |= 1 << (j % 64)
+	} else {
+		b.row(i)[j/64] &^= 1 << (j % 64)
+	}
+}
+
+// Count returns the number of true elements.
+func (b BitMat) Count() int {
+	n := 0
+	for _, w := range b.Data {
+		n += bits.OnesCount64(w)
+	}
+	return n
+}
+
+// And returns the element-wise conjunction of two matrices: r = b∧n
+func (b BitMat) And(n BitMat) BitMat {
+	return b.zip(n, func(x, y uint64) uint64 { return x & y })
+}
+
+// Or returns the element-wise disjunction of two matrices: r = b∨n
+func (b BitMat) Or(n BitMat) BitMat {
+	return b.zip(n, func(x, y uint64) uint64 { return x | y })
+}
+
+// Xor returns the element-wise exclusive disjunction of two
+// matrices: r = b⊕n
+func (b BitMat) Xor(n BitMat) BitMat {
+	return b.zip(n, func(x, y uint64) uint64 { return x ^ y })
+}
+
+// T returns the transposed matrix.
+func (b BitMat) T() BitMat {
+	r := NewBitMat(b.Col, b.Row)
+	parallel(r.Row, func(j int) {
+		row := r.row(j)
+		for i := 0; i < b.Row; i++ {
+			row[i/64] |= b.row(i)[j/64] >> (j % 64) & 1 << (i % 64)
+		}
+	})
+	return r
+}
+
+// Mul returns the boolean product of two matrices, whose element (i, j)
+// is true if the i-th row of b and the j-th column of n have a common
+// true element, e.g. a path of two steps in a graph.
+//
+// The rows of the result are computed in parallel, and every element
+// compares 64 pairs of elements at once.
+func (b BitMat) Mul(n BitMat) BitMat {
+	if b.Col != n.Row {
+		panic("math: mismatched matrix dimension")
+	}
+
+	nt := n.T()
+	r := NewBitMat(b.Row, n.Col)
+	parallel(b.Row, func(i int) {
+		row, out := b.row(i), r.row(i)
+		for j := 0; j < n.Col; j++ {
+			col := nt.row(j)
+			for k := range row {
+				if row[k]&col[k] != 0 {
+					out[j/64] |= 1 << (j % 64)
+					break
+				}
+			}
+		}
+	})
+	return r
+}
+
+// MulCount returns the product of two matrices as integers, whose
+// element (i, j) counts the common true elements of the i-th row of b
+// and the j-th column of n, e.g. the size of the intersection of two
+// sets. The counts are computed by the population count of words.
+func (b BitMat) MulCount(n BitMat) Mat[int32] {
+	if b.Col != n.Row {
+		panic("math: mismatched matrix dimension")
+	}
+
+	nt := n.T()
+	r := Mat[int32]{Row: b.Row, Col: n.Col, Data: make([]int32, b.Row*n.Col)}
+	parallel(b.Row, func(i int) {
+		row := b.row(i)
+		for j := 0; j < n.Col; j++ {
+			col, c := nt.row(j), 0
+			for k := range row {
+				c += bits.OnesCount64(row[k] & col[k])
+			}
+			r.Data[i*n.Col+j] = int32(c)
+		}
+	})
+	return r
+}
+
+func (b BitMat) zip(n BitMat, f func(x, y uint64) uint64) BitMat {
+	if b.Row != n.Row || b.Col != n.Col {
+		panic("math: mismatched matrix dimension")
+	}
+
+	r := NewBitMat(b.Row, b.Col)
+	parallel(b.Row, func(i int) {
+		x, y, out := b.row(i), n.row(i), r.row(i)
+		for k := range out {
+			out[k] = f(x[k], y[k])
+		}
+	})
+	return r
+}
+
+func (b BitMat) row(i int) []uint64 {
+	w := words(b.Col)
+	return b.Data[i*w : (i+1)*w]
+}
+
+func (b BitMat) check(i, j int) {
+	if i < 0 || i >= b.Row || j < 0 || j >= b.Col {
+		panic("math: index out of range")
+	}
+}
+
+// words returns the number of words to store col bits.
+func words(col int) int { return (col + 63) / 64 }
@@ -0,0 +1,91 @@
+// Copyright 2023 Changkun Ou <changkun.de>. All rights reserved.
+// Use of this source code is governed by a MIT license that
+// can be found in the LICENSE file.
+
+package math
+
+// Cholesky is the Cholesky decomposition of a symmetric positive definite
+// matrix A, such that A = L*L' where L is a lower triangular matrix with
+// positive diagonal elements.
+type Cholesky[T Float] struct {
+	l Mat[T]
+}
+
+// FactorCholesky computes the Cholesky decomposition of a square matrix a.
+// It returns ErrNotPositiveDefinite if a is not symmetric or not positive
+// definite.
+func FactorCholesky[T Float](a Mat[T]) (*Cholesky[T], error) {
+	if a.Row != a.Col {
+		panic("math: matrix is not square")
+	}
+
+	n := a.Row
+	if !symmetric(a) {
+		return nil, ErrNotPositiveDefinite
+	}
+
+	l := Mat[T]{Row: n, Col: n, Data: make([]T, n*n)}
+	for j := 0; j < n; j++ {
+		d := a.Get(j, j)
+		for k := 0; k < j; k++ {
+			d -= l.Get(j, k) * l.Get(j, k)
+		}
+		if d <= 0 {
+			return nil, ErrNotPositiveDefinite
+		}
+		d = sqrt(d)
+		l.Set(j, j, d)
+
+		for i := j + 1; i < n; i++ {
+			v := a.Get(i, j)
+			for k := 0; k < j; k++ {
+				v -= l.Get(i, k) * l.Get(j, k)
+			}
+			l.Set(i, j, v/d)
+		}
+	}
+	return &Cholesky[T]{l: l}, nil
+}
+
+// L returns the lower triangular factor.
+func (c *Cholesky[T]) L() Mat[T] { return c.l.Clone() }
+
+// Solve solves the linear system A*X = B for X, where every column of b
+// is a right-hand side.
+func (c *Cholesky[T]) Solve(b Mat[T]) Mat[T] {
+	// The diagonal of L is positive, hence both solves succeed.
+	y, _ := SolveLower(c.l, b)
+	x, _ := SolveUpper(transpose(c.l), y)
+	return x
+}
+
+// Det returns the determinant of the factorized matrix.
+func (c *Cholesky[T]) Det() T {
+	d := T(1)
+	for i := 0; i < c.l.Row; i++ {
+		d *= c.l.Get(i, i) * c.l.Get(i, i)
+	}
+	return d
+}
+
+// LogDet returns the natural logarithm of the determinant of the
+// factorized matrix, which does not overflow for large matrices as
+// Det does.
+func (c *Cholesky[T]) LogDet() T {
+	var d T
+	for i := 0; i < c.l.Row; i++ {
+		d += log(c.l.Get(i, i))
+	}
+	return 2 * d
+}
+
+// SolveSPD solves the linear system a*x = b for x using the Cholesky
+// decomposition of a. It returns ErrNotPositiveDefinite if a is not
+// symmetric positive definite.
+func SolveSPD[T Float](a, b Mat[T]) (Mat[T], error) {
+	c, err := FactorCholesky(a)
+	if err != nil {
+		return Mat[T]{}, err
+	}
+	return c.Solve(b), nil
+}
@@ -0,0 +1,203 @@
+// Copyright 2023 Changkun Ou <changkun.de>. All rights reserved.
+// Use of this source code is governed by a MIT license that
+// can be found in the LICENSE file.
+
+package math
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// CompareOptions configures the tolerances of Compare. Two elements match
+// if they are equal, or if their difference is within any of the
+// non-zero tolerances. A NaN never matches.
+type CompareOptions struct {
+	// Abs is the absolute tolerance: |a-b| <= Abs.
+	Abs float64
+	// Rel is the tolerance relative to the larger magnitude of the
+	// two elements: |a-b| <= Rel*max(|a|, |b|).
+	Rel float64
+	// ULP is the tolerance in units in the last place, i.e. the number
+	// of representable values between the two elements. For complex
+	// types it applies to the real and the imaginary parts, and for
+	// integer types a unit is one.
+	ULP uint64
+	// MaxPositions is the number of mismatching positions recorded by
+	// the report, 10 if zero.
+	MaxPositions int
+}
+
+// CompareReport summarizes the element-wise differences of two matrices.
+type CompareReport struct {
+	Row, Col   int
+	Mismatches int      // number of mismatching elements
+	Positions  [][2]int // first mismatching positions in row-major order
+	MaxAbsErr  float64  // largest absolute error |a-b|
+	MaxAbsAt   [2]int   // position of the largest absolute error
+	MaxRelErr  float64  // largest relative error |a-b|/max(|a|, |b|)
+	MaxRelAt   [2]int   // position of the largest relative error
+	MaxULP     uint64   // largest error in units in the last place
+	NaN        int      // number of positions where any element is NaN
+	Inf        int      // number of positions where any element is infinite
+}
+
+// Equal returns true if all elements match.
+func (r CompareReport) Equal() bool { return r.Mismatches == 0 }
+
+// String returns a human readable summary of the report.
+func (r CompareReport) String() string {
+	if r.Equal() {
+		return fmt.Sprintf("%dx%d matrices are equal: max abs error %g at %v, max rel error %g at %v, max ulp %d",
+			r.Row, r.Col, r.MaxAbsErr, r.MaxAbsAt, r.MaxRelErr, r.MaxRelAt, r.MaxULP)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d of %dx%d elements mismatch at %v", r.Mismatches, r.Row, r.Col, r.Positions)
+	if r.Mismatches > len(r.Positions) {
+		b.WriteString("...")
+	}
+	fmt.Fprintf(&b, ": max abs error %g at %v, max rel error %g at %v, max ulp %d",
+		r.MaxAbsErr, r.MaxAbsAt, r.MaxRelErr, r.MaxRelAt, r.MaxULP)
+	if r.NaN > 0 || r.Inf > 0 {
+		fmt.Fprintf(&b, ", %d NaN and %d Inf", r.NaN, r.Inf)
+	}
+	return b.String()
+}
+
+// Compare compares two matrices of the same shape element-wise with the
+// given tolerances, and returns a report of their differences. The
+// maximum errors of the report exclude the positions of NaN and
+// infinite elements, and infinities only match if they are equal.
+func Compare[T Elem](a, b Mat[T], opts CompareOptions) CompareReport {
+	if a.Row != b.Row || a.Col != b.Col {
+		panic("math: mismatched matrix dimension")
+	}
+	if opts.MaxPositions == 0 {
+		opts.MaxPositions = 10
+	}
+
+	var zero T
+	dist, ulp := distFunc[T](), ulpFunc[T]()
+	r := CompareReport{Row: a.Row, Col: a.Col}
+	for i := 0; i < a.Row; i++ {
+		for j := 0; j < a.Col; j++ {
+			x, y := a.Get(i, j), b.Get(i, j)
+			mx, my := dist(x, zero), dist(y, zero)
+			d := dist(x, y)
+
+			nan := mx != mx || my != my
+			match := !nan && (x == y || d <= opts.Abs)
+			switch {
+			case nan:
+				r.NaN++
+			case math.IsInf(mx, 0) || math.IsInf(my, 0):
+				r.Inf++
+			default:
+				rel := d / math.Max(mx, my)
+				if d == 0 {
+					rel = 0
+				}
+				u := ulp(x, y)
+				match = match || rel <= opts.Rel || u <= opts.ULP
+				if d > r.MaxAbsErr {
+					r.MaxAbsErr, r.MaxAbsAt = d, [2]int{i, j}
+				}
+				if rel > r.MaxRelErr {
+					r.MaxRelErr, r.MaxRelAt = rel, [2]int{i, j}
+				}
+				if u > r.MaxULP {
+					r.MaxULP = u
+				}
+			}
+			if !match {
+				r.Mismatches++
+				if len(r.Positions) < opts.MaxPositions {
+					r.Positions = append(r.Positions, [2]int{i, j})
+				}
+			}
+		}
+	}
+	return r
+}
+
+// ulpFunc returns the function that computes the number of representable
+// values between two values of type T.
+func ulpFunc[T Elem]() func(a, b T) uint64 {
+	var v T
+	switch any(v).(type) {
+	case float32:
+		return func(a, b T) uint64 { return ulp32(as[float32](a), as[float32](b)) }
+	case float64:
+		return func(a, b T) uint64 { return ulp64(as[float64](a), as[float64](b)) }
+	case complex64:
+		return func(a, b T) uint64 {
+			x, y := as[complex64](a), as[complex64](b)
+			return max64(ulp32(real(x), real(y)), ulp32(imag(x), imag(y)))
+		}
+	case complex128:
+		return func(a, b T) uint64 {
+			x, y := as[complex128](a), as[complex128](b)
+			return max64(ulp64(real(x), real(y)), ulp64(imag(x), imag(y)))
+		}
+	case Float16:
+		return func(a, b T) uint64 { return ulp16(uint16(as[Float16](a)), uint16(as[Float16](b))) }
+	case BFloat16:
+		return func(a, b T) uint64 { return ulp16(uint16(as[BFloat16](a)), uint16(as[BFloat16](b))) }
+	}
+	dist := distFunc[T]()
+	return func(a, b T) uint64 { return uint64(dist(a, b)) }
+}
+
+// The following functions map the bits of floating-point numbers to
+// integers that are ordered like the numbers, where the positive and
+// the negative zeros are the same, and return the distance.
+
+func ulp16(a, b uint16) uint64 {
+	ord := func(v uint16) int64 {
+		if v&0x8000 != 0 {
+			return -int64(v & 0x7fff)
+		}
+		return int64(v)
+	}
+	return absDiff(ord(a), ord(b))
+}
+
+func ulp32(a, b float32) uint64 {
+	ord := func(f float32) int64 {
+		v := math.Float32bits(f)
+		if v&(1<<31) != 0 {
+			return -int64(v &^ (1 << 31))
+		}
+		return int64(v)
+	}
+	return absDiff(ord(a), ord(b))
+}
+
+func ulp64(a, b float64) uint64 {
+	// The distance between two float64 may exceed the range of int64,
+	// hence the magnitudes are subtracted as unsigned integers.
+	x, y := math.Float64bits(a), math.Float64bits(b)
+	mx, my := x&^(1<<63), y&^(1<<63)
+	if x>>63 != y>>63 {
+		return mx + my
+	}
+	if mx > my {
+		return mx - my
+	}
+	return my - mx
+}
+
+func absDiff(a, b int64) uint64 {
+	if a > b {
+		return uint64(a - b)
+	}
+	return uint64(b - a)
+}
+
+func max64(a, b uint64) uint64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,66 @@
+// Copyright 2023 Changkun Ou <changkun.de>. All rights reserved.
+// Use of this source code is governed by a MIT license that
+// can be found in the LICENSE file.
+
+package math
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ReadCSV reads a dense matrix from comma-separated values, where every
+// record is a row and all records have the same number of fields. The
+// records are parsed as they are read, and a malformed record results in
+// a *ParseError.
+func ReadCSV[T Elem](r io.Reader) (Mat[T], error) {
+	cr := csv.NewReader(r)
+	cr.ReuseRecord = true
+	parse := parseFunc[T]()
+
+	var m Mat[T]
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return m, nil
+		}
+		var pe *csv.ParseError
+		if errors.As(err, &pe) {
+			return Mat[T]{}, &ParseError{Format: "csv", Line: pe.Line, Err: pe.Err}
+		}
+		if err != nil {
+			return Mat[T]{}, err
+		}
+
+		for j, f := range rec {
+			v, err := parse(strings.TrimSpace(f))
+			if err != nil {
+				line, _ := cr.FieldPos(j)
+				return Mat[T]{}, &ParseError{Format: "csv", Line: line, Err: fmt.Errorf("field %d: %v", j+1, err)}
+			}
+			m.Data = append(m.Data, v)
+		}
+		m.Row, m.Col = m.Row+1, len(rec)
+	}
+}
+
+// WriteCSV writes a matrix as comma-separated values, one record per row.
+// Complex values are written as "(re+imi)".
+func WriteCSV[T Elem](w io.Writer, m Mat[T]) error {
+	cw := csv.NewWriter(w)
+	format := formatFunc[T]()
+	rec := make([]string, m.Col)
+	for i := 0; i < m.Row; i++ {
+		for j := range rec {
+			rec[j] = format(m.Get(i, j))
+		}
+		if err := cw.Write(rec); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,109 @@
+// Copyright 2023 Changkun Ou <changkun.de>. All rights reserved.
+// Use of this source code is governed by a MIT license that
+// can be found in the LICENSE file.
+
+package math
+
+import "sort"
+
+// maxSweeps is the maximum number of sweeps of the Jacobi methods.
+const maxSweeps = 60
+
+// Eigen is the eigen-decomposition of a symmetric matrix A, such that
+// A = V*diag(λ)*V' where the columns of the orthogonal matrix V are the
+// eigenvectors and λ are the eigenvalues in descending order.
+type Eigen[T Float] struct {
+	values  []T
+	vectors Mat[T]
+}
+
+// FactorEigenSym computes the eigen-decomposition of a symmetric matrix
+// a using the cyclic Jacobi method. It returns ErrNotSymmetric if a is
+// not symmetric.
+//
+// If mul is not nil, the rotated matrix V'*A*V is recomputed by mul at
+// the beginning of every sweep, which discards the rounding errors that
+// the rotations accumulate, and allows the multiplications to run on a
+// GPU by passing gpu.Mul.
+func FactorEigenSym[T Float](a Mat[T], mul MulFunc[T]) (*Eigen[T], error) {
+	if !symmetric(a) {
+		return nil, ErrNotSymmetric
+	}
+
+	n := a.Row
+	b := a.Clone()
+	v := Mat[T]{Row: n, Col: n, Data: make([]T, n*n)}
+	for i := 0; i < n; i++ {
+		v.Set(i, i, 1)
+	}
+
+	tol := T(n) * epsilon[T]() * frobenius(a)
+	converged := false
+	for sweep := 0; sweep < maxSweeps && !converged; sweep++ {
+		if mul != nil && sweep > 0 {
+			b = mul(mul(transpose(v), a), v)
+		}
+
+		converged = true
+		for p := 0; p < n-1; p++ {
+			for q := p + 1; q < n; q++ {
+				bpq := b.Get(p, q)
+				if abs(bpq) <= tol {
+					continue
+				}
+				converged = false
+
+				c, s := rotation(b.Get(p, p), b.Get(q, q), bpq)
+				rotate(b, p, q, c, s)
+				for k := 0; k < n; k++ {
+					bp, bq := b.Get(p, k), b.Get(q, k)
+					b.Set(p, k, c*bp-s*bq)
+					b.Set(q, k, s*bp+c*bq)
+				}
+				rotate(v, p, q, c, s)
+			}
+		}
+	}
+	if !converged {
+		return nil, ErrNoConvergence
+	}
+
+	values := make([]T, n)
+	for i := range values {
+		values[i] = b.Get(i, i)
+	}
+	values = sortColumns(values, &v)
+	return &Eigen[T]{values: values, vectors: v}, nil
+}
+
+// Values returns the eigenvalues in descending order.
+func (e *Eigen[T]) Values() []T { return append([]T(nil), e.values...) }
+
+// Vectors returns the eigenvectors as the columns of a matrix, in the
+// same order as the eigenvalues.
+func (e *Eigen[T]) Vectors() Mat[T] { return e.vectors.Clone() }
+
+// sortColumns sorts the values in descending order and reorders the
+// columns of the given matrices accordingly.
+func sortColumns[T Float](values []T, ms ...*Mat[T]) []T {
+	idx := make([]int, len(values))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(i, j int) bool { return values[idx[i]] > values[idx[j]] })
+
+	vs := make([]T, len(values))
+	for j, k := range idx {
+		vs[j] = values[k]
+	}
+	for _, m := range ms {
+		r := Mat[T]{Row: m.Row, Col: m.Col, Data: make([]T, m.Row*m.Col)}
+		for j, k := range idx {
+			for i := 0; i < m.Row; i++ {
+				r.Set(i, j, m.Get(i, k))
+			}
+		}
+		*m = r
+	}
+	return vs
+}
@@ -0,0 +1,93 @@
+// Copyright 2023 Changkun Ou <changkun.de>. All rights reserved.
+// Use of this source code is governed by a MIT license that
+// can be found in the LICENSE file.
+
+package math
+
+// Add returns the element-wise sum of two matrices: r = m+n
+//
+// The two matrices are broadcast against each other: a dimension of
+// size 1 is stretched to the size of the same dimension of the other
+// matrix. Hence, a 1xN row vector is added to every row and a Mx1
+// column vector to every column.
+func (m Mat[T]) Add(n Mat[T]) Mat[T] {
+	return m.zip(n, func(a, b T) T { return a + b })
+}
+
+// Sub returns the element-wise difference of two matrices: r = m-n
+//
+// The two matrices are broadcast against each other as in Add.
+func (m Mat[T]) Sub(n Mat[T]) Mat[T] {
+	return m.zip(n, func(a, b T) T { return a - b })
+}
+
+// Hadamard returns the element-wise product of two matrices: r = m∘n
+//
+// The two matrices are broadcast against each other as in Add.
+func (m Mat[T]) Hadamard(n Mat[T]) Mat[T] {
+	return m.zip(n, func(a, b T) T { return a * b })
+}
+
+// Scale returns the matrix multiplied by a scalar: r = s*m
+func (m Mat[T]) Scale(s T) Mat[T] {
+	return m.Apply(func(v T) T { return v * s })
+}
+
+// Apply returns a new matrix that applies f to every element of m.
+func (m Mat[T]) Apply(f func(T) T) Mat[T] {
+	arithmetic[T]()
+	r := Mat[T]{
+		Row:  m.Row,
+		Col:  m.Col,
+		Data: make([]T, m.Row*m.Col),
+	}
+	for i := 0; i < m.Row; i++ {
+		for j := 0; j < m.Col; j++ {
+			r.Data[i*r.Col+j] = f(m.Get(i, j))
+		}
+	}
+	return r
+}
+
+// Broadcast returns the shape of the result of an element-wise operation
+// between two matrices of the given shapes. It panics if the shapes are
+// not compatible.
+func Broadcast(row1, col1, row2, col2 int) (row, col int) {
+	dim := func(a, b int) int {
+		switch {
+		case a == b || b == 1:
+			return a
+		case a == 1:
+			return b
+		}
+		panic("math: mismatched matrix dimension")
+	}
+	return dim(row1, row2), dim(col1, col2)
+}
+
+func (m Mat[T]) zip(n Mat[T], f func(a, b T) T) Mat[T] {
+	arithmetic[T]()
+	row, col := Broadcast(m.Row, m.Col, n.Row, n.Col)
+	r := Mat[T]{
+		Row:  row,
+		Col:  col,
+		Data: make([]T, row*col),
+	}
+
+	// A broadcast dimension always reads its first element.
+	at := func(x Mat[T], i, j int) T {
+		if x.Row == 1 {
+			i = 0
+		}
+		if x.Col == 1 {
+			j = 0
+		}
+		return x.Get(i, j)
+	}
+	for i := 0; i < row; i++ {
+		for j := 0; j < col; j++ {
+			r.Data[i*col+j] = f(at(m, i, j), at(n, i, j))
+		}
+	}
+	return r
+}
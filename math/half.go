@@ -0,0 +1,170 @@
+// Copyright 2023 Changkun Ou <changkun.de>. All rights reserved.
+// Use of this source code is governed by a MIT license that
+// can be found in the LICENSE file.
+
+package math
+
+import "math"
+
+// Float16 is an IEEE 754 half-precision floating-point number, which has
+// 1 sign bit, 5 exponent bits and 10 mantissa bits.
+//
+// A Float16 only stores the bits of the number, hence the arithmetic
+// operators do not apply to it. A Mat[Float16] is a storage format: it
+// is multiplied by MulMixed, or converted by ToFloat32 and FromFloat32.
+type Float16 uint16
+
+// BFloat16 is a brain floating-point number, which has 1 sign bit,
+// 8 exponent bits and 7 mantissa bits, i.e. the upper half of a float32.
+// As for Float16, the arithmetic operators do not apply to it.
+type BFloat16 uint16
+
+// Half defines the half-precision floating-point types.
+type Half interface {
+	Float16 | BFloat16
+}
+
+// NewFloat16 converts a float32 to the nearest Float16, rounding ties
+// to even. Values beyond the range of Float16 become infinities.
+func NewFloat16(f float32) Float16 {
+	b := math.Float32bits(f)
+	sign := uint16(b>>16) & 0x8000
+	exp := int(b>>23) & 0xff
+	man := b & 0x7fffff
+
+	if exp == 0xff {
+		if man != 0 {
+			// Keep the upper bits of the payload and make it quiet.
+			return Float16(sign | 0x7e00 | uint16(man>>13))
+		}
+		return Float16(sign | 0x7c00)
+	}
+
+	e := exp - 127 + 15
+	switch {
+	case e >= 0x1f:
+		return Float16(sign | 0x7c00)
+	case e <= 0:
+		// The result is subnormal, which has no implicit leading bit
+		// and a fixed exponent of -14.
+		if e < -10 {
+			return Float16(sign)
+		}
+		return Float16(sign | uint16(roundEven(man|0x800000, uint(14-e))))
+	}
+	// A carry of the rounding propagates into the exponent and
+	// eventually results in an infinity.
+	return Float16(sign | uint16(roundEven(uint32(e)<<23|man, 13)))
+}
+
+// Float32 converts a Float16 to a float32, which is always exact.
+func (h Float16) Float32() float32 {
+	sign := uint32(h&0x8000) << 16
+	exp := uint32(h>>10) & 0x1f
+	man := uint32(h & 0x3ff)
+
+	switch exp {
+	case 0x1f:
+		return math.Float32frombits(sign | 0x7f800000 | man<<13)
+	case 0:
+		if man == 0 {
+			return math.Float32frombits(sign)
+		}
+		// Normalize the subnormal number.
+		exp = 127 - 15 + 1
+		for man&0x400 == 0 {
+			man <<= 1
+			exp--
+		}
+		return math.Float32frombits(sign | exp<<23 | (man&0x3ff)<<13)
+	}
+	return math.Float32frombits(sign | (exp+127-15)<<23 | man<<13)
+}
+
+// NewBFloat16 converts a float32 to the nearest BFloat16, rounding ties
+// to even.
+func NewBFloat16(f float32) BFloat16 {
+	b := math.Float32bits(f)
+	if b&0x7fffffff > 0x7f800000 {
+		// Make a NaN quiet, so that it does not become an infinity
+		// when its payload is truncated.
+		return BFloat16(b>>16 | 0x40)
+	}
+	return BFloat16(roundEven(b, 16))
+}
+
+// Float32 converts a BFloat16 to a float32, which is always exact.
+func (h BFloat16) Float32() float32 {
+	return math.Float32frombits(uint32(h) << 16)
+}
+
+// roundEven shifts v to the right by n bits and rounds the result to
+// the nearest integer, ties to even.
+func roundEven(v uint32, n uint) uint32 {
+	q := v >> n
+	rem, half := v&(1<<n-1), uint32(1)<<(n-1)
+	if rem > half || (rem == half && q&1 == 1) {
+		q++
+	}
+	return q
+}
+
+// ToFloat32 converts a half-precision matrix to a dense float32 matrix.
+func ToFloat32[T Half](m Mat[T]) Mat[float32] {
+	conv := toFloat32Func[T]()
+	r := Mat[float32]{Row: m.Row, Col: m.Col, Data: make([]float32, m.Row*m.Col)}
+	for i := 0; i < m.Row; i++ {
+		for j := 0; j < m.Col; j++ {
+			r.Data[i*m.Col+j] = conv(m.Get(i, j))
+		}
+	}
+	return r
+}
+
+// FromFloat32 converts a float32 matrix to a dense half-precision matrix
+// with correctly rounded elements.
+func FromFloat32[T Half](m Mat[float32]) Mat[T] {
+	conv := fromFloat32Func[T]()
+	r := Mat[T]{Row: m.Row, Col: m.Col, Data: make([]T, m.Row*m.Col)}
+	for i := 0; i < m.Row; i++ {
+		for j := 0; j < m.Col; j++ {
+			r.Data[i*m.Col+j] = conv(m.Get(i, j))
+		}
+	}
+	return r
+}
+
+// MulMixed multiplies two half-precision matrices with float32
+// accumulation, and returns the float32 result: r = m*n
+//
+// The inputs are widened to float32, which is exact, and then multiplied
+// by the blocked Mul. Hence the result only differs from a float32
+// multiplication by the rounding of the inputs to half-precision.
+func MulMixed[T Half](m, n Mat[T]) Mat[float32] {
+	if m.Col != n.Row {
+		panic("math: mismatched matrix dimension")
+	}
+	return ToFloat32(m).Mul(ToFloat32(n))
+}
+
+func toFloat32Func[T Half]() func(v T) float32 {
+	var v T
+	switch any(v).(type) {
+	case Float16:
+		return func(v T) float32 { return Float16(v).Float32() }
+	case BFloat16:
+		return func(v T) float32 { return BFloat16(v).Float32() }
+	}
+	panic("unknown conversion for type")
+}
+
+func fromFloat32Func[T Half]() func(f float32) T {
+	var v T
+	switch any(v).(type) {
+	case Float16:
+		return func(f float32) T { return T(NewFloat16(f)) }
+	case BFloat16:
+		return func(f float32) T { return T(NewBFloat16(f)) }
+	}
+	panic("unknown conversion for type")
+}
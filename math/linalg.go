@@ -0,0 +1,188 @@
+// Copyright 2023 Changkun Ou <changkun.de>. All rights reserved.
+// Use of this source code is governed by a MIT license that
+// can be found in the LICENSE file.
+
+package math
+
+import (
+	"errors"
+	"math"
+)
+
+// Float defines all supported floating-point types for matrix
+// decompositions and linear solvers.
+type Float interface {
+	~float32 | ~float64
+}
+
+// ErrSingular is returned if a linear system cannot be solved because
+// its matrix is singular to working precision.
+var ErrSingular = errors.New("math: matrix is singular")
+
+// ErrRankDeficient is returned if a least-squares problem cannot be
+// solved because its matrix does not have full column rank.
+var ErrRankDeficient = errors.New("math: matrix is rank deficient")
+
+// ErrNotPositiveDefinite is returned if a matrix is expected to be
+// symmetric positive definite but is not.
+var ErrNotPositiveDefinite = errors.New("math: matrix is not symmetric positive definite")
+
+// ErrNotSymmetric is returned if a matrix is expected to be symmetric
+// but is not.
+var ErrNotSymmetric = errors.New("math: matrix is not symmetric")
+
+// ErrNoConvergence is returned if an iterative algorithm does not
+// converge within its maximum number of iterations.
+var ErrNoConvergence = errors.New("math: algorithm does not converge")
+
+// epsilon returns the machine epsilon of the given floating-point type.
+func epsilon[T Float]() T {
+	var v T
+	switch any(v).(type) {
+	case float32:
+		return 0x1p-23
+	case float64:
+		return 0x1p-52
+	}
+	panic("unknown machine epsilon for type")
+}
+
+func abs[T Float](v T) T {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func sqrt[T Float](v T) T {
+	return T(math.Sqrt(float64(v)))
+}
+
+func log[T Float](v T) T {
+	return T(math.Log(float64(v)))
+}
+
+func hypot[T Float](a, b T) T {
+	return T(math.Hypot(float64(a), float64(b)))
+}
+
+// maxAbs returns the largest absolute value of the elements of m.
+func maxAbs[T Float](m Mat[T]) T {
+	var r T
+	for i := 0; i < m.Row; i++ {
+		for j := 0; j < m.Col; j++ {
+			if v := abs(m.Get(i, j)); v > r {
+				r = v
+			}
+		}
+	}
+	return r
+}
+
+// symmetric returns true if a is a square matrix that is symmetric
+// to working precision.
+func symmetric[T Float](a Mat[T]) bool {
+	if a.Row != a.Col {
+		return false
+	}
+	tol := T(a.Row) * epsilon[T]() * maxAbs(a)
+	for i := 0; i < a.Row; i++ {
+		for j := 0; j < i; j++ {
+			if abs(a.Get(i, j)-a.Get(j, i)) > tol {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// frobenius returns the Frobenius norm of m.
+func frobenius[T Float](m Mat[T]) T {
+	var s T
+	for i := 0; i < m.Row; i++ {
+		for j := 0; j < m.Col; j++ {
+			s += m.Get(i, j) * m.Get(i, j)
+		}
+	}
+	return sqrt(s)
+}
+
+// transpose returns a dense transposed copy of m.
+func transpose[T Type](m Mat[T]) Mat[T] {
+	return m.View().T().Clone()
+}
+
+// rotation returns the cosine and sine of the Jacobi rotation that
+// diagonalizes the symmetric 2x2 matrix [app apq; apq aqq].
+func rotation[T Float](app, aqq, apq T) (c, s T) {
+	tau := (aqq - app) / (2 * apq)
+	t := 1 / (abs(tau) + sqrt(1+tau*tau))
+	if tau < 0 {
+		t = -t
+	}
+	c = 1 / sqrt(1+t*t)
+	return c, t * c
+}
+
+// rotate applies a Jacobi rotation to the columns p and q of m.
+func rotate[T Float](m Mat[T], p, q int, c, s T) {
+	for k := 0; k < m.Row; k++ {
+		mp, mq := m.Get(k, p), m.Get(k, q)
+		m.Set(k, p, c*mp-s*mq)
+		m.Set(k, q, s*mp+c*mq)
+	}
+}
+
+// SolveLower solves the linear system l*x = b for x by forward
+// substitution, where l is a lower triangular matrix whose elements
+// above the diagonal are ignored. It returns ErrSingular if l has a
+// zero on its diagonal.
+func SolveLower[T Float](l, b Mat[T]) (Mat[T], error) {
+	n := l.Row
+	if l.Col != n || b.Row != n {
+		panic("math: mismatched matrix dimension")
+	}
+
+	x := b.Clone()
+	for j := 0; j < x.Col; j++ {
+		for i := 0; i < n; i++ {
+			d := l.Get(i, i)
+			if d == 0 {
+				return Mat[T]{}, ErrSingular
+			}
+			v := x.Get(i, j)
+			for k := 0; k < i; k++ {
+				v -= l.Get(i, k) * x.Get(k, j)
+			}
+			x.Set(i, j, v/d)
+		}
+	}
+	return x, nil
+}
+
+// SolveUpper solves the linear system u*x = b for x by back
+// substitution, where u is an upper triangular matrix whose elements
+// below the diagonal are ignored. It returns ErrSingular if u has a
+// zero on its diagonal.
+func SolveUpper[T Float](u, b Mat[T]) (Mat[T], error) {
+	n := u.Row
+	if u.Col != n || b.Row != n {
+		panic("math: mismatched matrix dimension")
+	}
+
+	x := b.Clone()
+	for j := 0; j < x.Col; j++ {
+		for i := n - 1; i >= 0; i-- {
+			d := u.Get(i, i)
+			if d == 0 {
+				return Mat[T]{}, ErrSingular
+			}
+			v := x.Get(i, j)
+			for k := i + 1; k < n; k++ {
+				v -= u.Get(i, k) * x.Get(k, j)
+			}
+			x.Set(i, j, v/d)
+		}
+	}
+	return x, nil
+}
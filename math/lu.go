@@ -0,0 +1,174 @@
+// Copyright 2023 Changkun Ou <changkun.de>. All rights reserved.
+// Use of this source code is governed by a MIT license that
+// can be found in the LICENSE file.
+
+package math
+
+// LU is the LU decomposition of a square matrix A with partial pivoting,
+// such that P*A = L*U where P is a permutation matrix, L is a unit lower
+// triangular matrix and U is an upper triangular matrix.
+type LU[T Float] struct {
+	lu       Mat[T] // L below the diagonal and U on and above it
+	piv      []int  // row i of P*A is row piv[i] of A
+	sign     T      // determinant of P
+	singular bool
+}
+
+// FactorLU computes the LU decomposition of a square matrix a.
+func FactorLU[T Float](a Mat[T]) *LU[T] {
+	if a.Row != a.Col {
+		panic("math: matrix is not square")
+	}
+
+	n := a.Row
+	f := &LU[T]{lu: a.Clone(), piv: make([]int, n), sign: 1}
+	for i := range f.piv {
+		f.piv[i] = i
+	}
+
+	// A pivot below this tolerance is treated as zero.
+	tol := T(n) * epsilon[T]() * maxAbs(a)
+
+	lu := f.lu
+	for k := 0; k < n; k++ {
+		p := k
+		for i := k + 1; i < n; i++ {
+			if abs(lu.Get(i, k)) > abs(lu.Get(p, k)) {
+				p = i
+			}
+		}
+		if p != k {
+			for j := 0; j < n; j++ {
+				v := lu.Get(p, j)
+				lu.Set(p, j, lu.Get(k, j))
+				lu.Set(k, j, v)
+			}
+			f.piv[p], f.piv[k] = f.piv[k], f.piv[p]
+			f.sign = -f.sign
+		}
+
+		pivot := lu.Get(k, k)
+		if abs(pivot) <= tol {
+			f.singular = true
+			continue
+		}
+		for i := k + 1; i < n; i++ {
+			l := lu.Get(i, k) / pivot
+			lu.Set(i, k, l)
+			for j := k + 1; j < n; j++ {
+				lu.Set(i, j, lu.Get(i, j)-l*lu.Get(k, j))
+			}
+		}
+	}
+	return f
+}
+
+// Singular returns true if the factorized matrix is singular to
+// working precision.
+func (f *LU[T]) Singular() bool { return f.singular }
+
+// L returns the unit lower triangular factor.
+func (f *LU[T]) L() Mat[T] {
+	n := f.lu.Row
+	l := Mat[T]{Row: n, Col: n, Data: make([]T, n*n)}
+	for i := 0; i < n; i++ {
+		for j := 0; j < i; j++ {
+			l.Set(i, j, f.lu.Get(i, j))
+		}
+		l.Set(i, i, 1)
+	}
+	return l
+}
+
+// U returns the upper triangular factor.
+func (f *LU[T]) U() Mat[T] {
+	n := f.lu.Row
+	u := Mat[T]{Row: n, Col: n, Data: make([]T, n*n)}
+	for i := 0; i < n; i++ {
+		for j := i; j < n; j++ {
+			u.Set(i, j, f.lu.Get(i, j))
+		}
+	}
+	return u
+}
+
+// Pivot returns the row permutation of the decomposition: row i of P*A
+// is row Pivot()[i] of A.
+func (f *LU[T]) Pivot() []int {
+	return append([]int(nil), f.piv...)
+}
+
+// Det returns the determinant of the factorized matrix.
+func (f *LU[T]) Det() T {
+	d := f.sign
+	for i := 0; i < f.lu.Row; i++ {
+		d *= f.lu.Get(i, i)
+	}
+	return d
+}
+
+// Solve solves the linear system A*X = B for X, where every column of b
+// is a right-hand side. It returns ErrSingular if A is singular.
+func (f *LU[T]) Solve(b Mat[T]) (Mat[T], error) {
+	n := f.lu.Row
+	if b.Row != n {
+		panic("math: mismatched matrix dimension")
+	}
+	if f.singular {
+		return Mat[T]{}, ErrSingular
+	}
+
+	x := Mat[T]{Row: n, Col: b.Col, Data: make([]T, n*b.Col)}
+	for i := 0; i < n; i++ {
+		for j := 0; j < b.Col; j++ {
+			x.Set(i, j, b.Get(f.piv[i], j))
+		}
+	}
+
+	// Forward substitution with L, then back substitution with U.
+	for j := 0; j < x.Col; j++ {
+		for i := 0; i < n; i++ {
+			v := x.Get(i, j)
+			for k := 0; k < i; k++ {
+				v -= f.lu.Get(i, k) * x.Get(k, j)
+			}
+			x.Set(i, j, v)
+		}
+		for i := n - 1; i >= 0; i-- {
+			v := x.Get(i, j)
+			for k := i + 1; k < n; k++ {
+				v -= f.lu.Get(i, k) * x.Get(k, j)
+			}
+			x.Set(i, j, v/f.lu.Get(i, i))
+		}
+	}
+	return x, nil
+}
+
+// Inverse returns the inverse of the factorized matrix. It returns
+// ErrSingular if the matrix is singular.
+func (f *LU[T]) Inverse() (Mat[T], error) {
+	n := f.lu.Row
+	id := Mat[T]{Row: n, Col: n, Data: make([]T, n*n)}
+	for i := 0; i < n; i++ {
+		id.Set(i, i, 1)
+	}
+	return f.Solve(id)
+}
+
+// Solve solves the linear system a*x = b for x using the LU decomposition
+// of a. It returns ErrSingular if a is singular.
+func Solve[T Float](a, b Mat[T]) (Mat[T], error) {
+	return FactorLU(a).Solve(b)
+}
+
+// Det returns the determinant of a square matrix a.
+func Det[T Float](a Mat[T]) T {
+	return FactorLU(a).Det()
+}
+
+// Inverse returns the inverse of a square matrix a. It returns
+// ErrSingular if a is singular.
+func Inverse[T Float](a Mat[T]) (Mat[T], error) {
+	return FactorLU(a).Inverse()
+}
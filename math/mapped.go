@@ -0,0 +1,144 @@
+// Copyright 2023 Changkun Ou <changkun.de>. All rights reserved.
+// Use of this source code is governed by a MIT license that
+// can be found in the LICENSE file.
+
+package math
+
+import (
+	"fmt"
+	"os"
+	"unsafe"
+)
+
+// Mapped is a matrix whose elements live in a memory-mapped file of the
+// binary encoding, see Mat.MarshalBinary. Its elements are paged in and
+// out by the operating system, hence the matrix can be larger than the
+// available memory. The embedded Mat is valid until Close.
+//
+// Writing an element of a read-only mapping crashes the program.
+type Mapped[T Elem] struct {
+	Mat[T]
+
+	file *os.File
+	data []byte
+}
+
+// MapOption configures the mapping of OpenMapped.
+type MapOption func(c *mapConfig)
+
+type mapConfig struct {
+	writable bool
+}
+
+// MapWritable maps the file for reading and writing, and the changes of
+// the elements are written back to the file.
+func MapWritable() MapOption {
+	return func(c *mapConfig) { c.writable = true }
+}
+
+// OpenMapped maps the matrix stored in the file at path, which is read
+// only by default. The file must hold a matrix of type T in the native
+// byte order.
+//
+// Memory mapping is only supported on Linux.
+func OpenMapped[T Elem](path string, opts ...MapOption) (*Mapped[T], error) {
+	var c mapConfig
+	for _, opt := range opts {
+		opt(&c)
+	}
+	flag := os.O_RDONLY
+	if c.writable {
+		flag = os.O_RDWR
+	}
+	f, err := os.OpenFile(path, flag, 0)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if fi.Size() < binaryHeader {
+		f.Close()
+		return nil, fmt.Errorf("%w: %s: missing header", ErrEncoding, path)
+	}
+
+	data, err := mmap(f, int(fi.Size()), c.writable)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	m := &Mapped[T]{file: f, data: data}
+	row, col, order, err := parseBinaryHeader[T](data)
+	if err == nil && len(data)-binaryHeader != row*col*TypeSize[T]() {
+		err = fmt.Errorf("%w: %d bytes of elements for a %dx%d matrix of %T",
+			ErrEncoding, len(data)-binaryHeader, row, col, *new(T))
+	}
+	if err == nil && order != nativeOrder() {
+		err = fmt.Errorf("%w: elements are not in the native byte order", ErrEncoding)
+	}
+	if err != nil {
+		m.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	m.Mat = Mat[T]{Row: row, Col: col, Data: m.elems(row * col)}
+	return m, nil
+}
+
+// CreateMapped creates or truncates the file at path to hold a zero
+// matrix of the given shape, and maps it for reading and writing. A
+// negative shape, or one whose size overflows, results in ErrEncoding
+// and leaves the file untouched.
+//
+// Memory mapping is only supported on Linux.
+func CreateMapped[T Elem](path string, row, col int) (*Mapped[T], error) {
+	n := TypeSize[T]()
+	if !fits(row, col, n) || row*col*n > maxInt-binaryHeader {
+		return nil, fmt.Errorf("%w: %s: invalid shape %dx%d of %T", ErrEncoding, path, row, col, *new(T))
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	size := binaryHeader + row*col*n
+	if err := f.Truncate(int64(size)); err != nil {
+		f.Close()
+		return nil, err
+	}
+	data, err := mmap(f, size, true)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	m := &Mapped[T]{file: f, data: data}
+	putBinaryHeader[T](data, row, col)
+	m.Mat = Mat[T]{Row: row, Col: col, Data: m.elems(row * col)}
+	return m, nil
+}
+
+// elems returns the n elements after the header. The mapping is aligned
+// to a page, hence the elements are aligned too.
+func (m *Mapped[T]) elems(n int) []T {
+	if n == 0 {
+		return nil
+	}
+	return unsafe.Slice((*T)(unsafe.Pointer(&m.data[binaryHeader])), n)
+}
+
+// Sync writes the changed elements of a writable mapping to the file.
+func (m *Mapped[T]) Sync() error {
+	return msync(m.data)
+}
+
+// Close unmaps the file and closes it. The elements of the matrix must
+// not be accessed after Close.
+func (m *Mapped[T]) Close() error {
+	m.Mat = Mat[T]{}
+	err := munmap(m.data)
+	m.data = nil
+	if cerr := m.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
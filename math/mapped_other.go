@@ -0,0 +1,22 @@
+// Copyright 2023 Changkun Ou <changkun.de>. All rights reserved.
+// Use of this source code is governed by a MIT license that
+// can be found in the LICENSE file.
+
+//go:build !linux
+
+package math
+
+import (
+	"errors"
+	"os"
+)
+
+var errMapUnsupported = errors.New("math: memory mapping is not supported on this platform")
+
+func mmap(f *os.File, size int, writable bool) ([]byte, error) {
+	return nil, errMapUnsupported
+}
+
+func munmap(b []byte) error { return nil }
+
+func msync(b []byte) error { return errMapUnsupported }
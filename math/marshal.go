@@ -0,0 +1,324 @@
+// Copyright 2023 Changkun Ou <changkun.de>. All rights reserved.
+// Use of this source code is governed by a MIT license that
+// can be found in the LICENSE file.
+
+package math
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// ErrEncoding is returned if the binary, gob or JSON encoding of a
+// matrix is malformed, or if it encodes a matrix of another type.
+var ErrEncoding = errors.New("math: invalid matrix encoding")
+
+// The binary encoding of a matrix consists of a fixed-size header and
+// the elements in row-major order. The header is laid out as follows,
+// where the shape is little-endian:
+//
+//	[0:4]   magic "GMAT"
+//	[4]     version, currently 1
+//	[5]     dtype, see dtypeOf
+//	[6]     byte order of the elements, '<' or '>'
+//	[7]     reserved, zero
+//	[8:16]  number of rows
+//	[16:24] number of columns
+//	[24:32] reserved, zero
+//
+// The header is 32 bytes such that the elements are aligned for every
+// element type if the encoding itself is aligned, e.g. in a mapped file.
+const (
+	binaryMagic   = "GMAT"
+	binaryVersion = 1
+	binaryHeader  = 32
+)
+
+// dtypeOf returns the code of T in the binary encoding. The codes are
+// part of the format and must never change.
+func dtypeOf[T Elem]() byte {
+	var v T
+	switch any(v).(type) {
+	case int8:
+		return 1
+	case uint8:
+		return 2
+	case int32:
+		return 3
+	case uint32:
+		return 4
+	case int64:
+		return 5
+	case float32:
+		return 6
+	case float64:
+		return 7
+	case complex64:
+		return 8
+	case complex128:
+		return 9
+	case Float16:
+		return 10
+	case BFloat16:
+		return 11
+	}
+	panic("unknown dtype for type")
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler. The elements are
+// written in the native byte order, and a view is written as a dense
+// matrix.
+func (m Mat[T]) MarshalBinary() ([]byte, error) {
+	if !m.Dense() {
+		m = m.Clone()
+	}
+	n := m.Row * m.Col
+	b := make([]byte, binaryHeader, binaryHeader+n*TypeSize[T]())
+	putBinaryHeader[T](b, m.Row, m.Col)
+	return append(b, bytesOf(m.Data[:n])...), nil
+}
+
+// putBinaryHeader writes the header of the binary encoding of a matrix
+// of the given shape, whose elements are in the native byte order.
+func putBinaryHeader[T Elem](b []byte, row, col int) {
+	copy(b, binaryMagic)
+	b[4] = binaryVersion
+	b[5] = dtypeOf[T]()
+	b[6] = '<'
+	if nativeOrder() == binary.BigEndian {
+		b[6] = '>'
+	}
+	binary.LittleEndian.PutUint64(b[8:], uint64(row))
+	binary.LittleEndian.PutUint64(b[16:], uint64(col))
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. The encoding
+// must hold a matrix of type T in any byte order, and the elements are
+// copied from b.
+func (m *Mat[T]) UnmarshalBinary(b []byte) error {
+	row, col, order, err := parseBinaryHeader[T](b)
+	if err != nil {
+		return err
+	}
+	data := make([]T, row*col)
+	d := bytesOf(data)
+	if len(b)-binaryHeader != len(d) {
+		return fmt.Errorf("%w: %d bytes of elements for a %dx%d matrix of %T",
+			ErrEncoding, len(b)-binaryHeader, row, col, *new(T))
+	}
+	copy(d, b[binaryHeader:])
+	if order != nativeOrder() {
+		swap[T](d)
+	}
+	*m = Mat[T]{Row: row, Col: col, Data: data}
+	return nil
+}
+
+// parseBinaryHeader validates the header of the binary encoding b and
+// returns the shape and the byte order of its elements.
+func parseBinaryHeader[T Elem](b []byte) (row, col int, order binary.ByteOrder, err error) {
+	if len(b) < binaryHeader || string(b[:4]) != binaryMagic {
+		return 0, 0, nil, fmt.Errorf("%w: missing header", ErrEncoding)
+	}
+	if b[4] != binaryVersion {
+		return 0, 0, nil, fmt.Errorf("%w: unsupported version %d", ErrEncoding, b[4])
+	}
+	if b[5] != dtypeOf[T]() {
+		return 0, 0, nil, fmt.Errorf("%w: dtype %d, want %T", ErrEncoding, b[5], *new(T))
+	}
+	switch b[6] {
+	case '<':
+		order = binary.LittleEndian
+	case '>':
+		order = binary.BigEndian
+	default:
+		return 0, 0, nil, fmt.Errorf("%w: unknown byte order %q", ErrEncoding, b[6])
+	}
+	if b[7] != 0 || binary.LittleEndian.Uint64(b[24:]) != 0 {
+		return 0, 0, nil, fmt.Errorf("%w: reserved header bytes are not zero", ErrEncoding)
+	}
+
+	r, c := binary.LittleEndian.Uint64(b[8:]), binary.LittleEndian.Uint64(b[16:])
+	limit := uint64(len(b)-binaryHeader) / uint64(TypeSize[T]())
+	if int(r) < 0 || int(c) < 0 || c != 0 && r > limit/c {
+		return 0, 0, nil, fmt.Errorf("%w: shape %dx%d exceeds %d elements", ErrEncoding, r, c, limit)
+	}
+	return int(r), int(c), order, nil
+}
+
+// GobEncode implements gob.GobEncoder with the binary encoding.
+func (m Mat[T]) GobEncode() ([]byte, error) { return m.MarshalBinary() }
+
+// GobDecode implements gob.GobDecoder with the binary encoding.
+func (m *Mat[T]) GobDecode(b []byte) error { return m.UnmarshalBinary(b) }
+
+// MarshalJSON implements json.Marshaler. A matrix is encoded as an
+// object of its shape and its elements in row-major order, e.g.
+//
+//	{"row":2,"col":2,"data":[1,2,3,4]}
+//
+// Every element is a number, including those of uint8 and the
+// half-precision types, and a complex element is an array of its real
+// and imaginary parts. NaN and infinities are encoded as the strings
+// "NaN", "+Inf" and "-Inf".
+func (m Mat[T]) MarshalJSON() ([]byte, error) {
+	app := appendJSONFunc[T]()
+	b := make([]byte, 0, 32+8*m.Row*m.Col)
+	b = append(b, `{"row":`...)
+	b = strconv.AppendInt(b, int64(m.Row), 10)
+	b = append(b, `,"col":`...)
+	b = strconv.AppendInt(b, int64(m.Col), 10)
+	b = append(b, `,"data":[`...)
+	for i := 0; i < m.Row; i++ {
+		for j := 0; j < m.Col; j++ {
+			if i != 0 || j != 0 {
+				b = append(b, ',')
+			}
+			b = app(b, m.Get(i, j))
+		}
+	}
+	return append(b, "]}"...), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler. All of row, col and data
+// must be present, and data must hold exactly row*col elements that are
+// representable by T.
+func (m *Mat[T]) UnmarshalJSON(b []byte) error {
+	var v struct {
+		Row  *int            `json:"row"`
+		Col  *int            `json:"col"`
+		Data json.RawMessage `json:"data"`
+	}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.DisallowUnknownFields()
+	if err := d.Decode(&v); err != nil {
+		return fmt.Errorf("%w: %v", ErrEncoding, err)
+	}
+	if v.Row == nil || v.Col == nil || v.Data == nil {
+		return fmt.Errorf("%w: row, col and data are required", ErrEncoding)
+	}
+	row, col := *v.Row, *v.Col
+	if row < 0 || col < 0 {
+		return fmt.Errorf("%w: negative shape %dx%d", ErrEncoding, row, col)
+	}
+
+	var elems []json.RawMessage
+	if err := json.Unmarshal(v.Data, &elems); err != nil {
+		return fmt.Errorf("%w: data: %v", ErrEncoding, err)
+	}
+	if row != 0 && (col > len(elems)/row || row*col != len(elems)) || row == 0 && len(elems) != 0 {
+		return fmt.Errorf("%w: %d elements for a %dx%d matrix", ErrEncoding, len(elems), row, col)
+	}
+
+	parse := parseJSONFunc[T]()
+	data := make([]T, len(elems))
+	for k, e := range elems {
+		val, err := parse(e)
+		if err != nil {
+			return fmt.Errorf("%w: data[%d]: %v", ErrEncoding, k, err)
+		}
+		data[k] = val
+	}
+	*m = Mat[T]{Row: row, Col: col, Data: data}
+	return nil
+}
+
+// appendJSONFunc returns the function that appends the JSON encoding of
+// a value of type T.
+func appendJSONFunc[T Elem]() func(b []byte, v T) []byte {
+	var v T
+	switch any(v).(type) {
+	case complex64, complex128:
+		bits := 4 * TypeSize[T]()
+		return func(b []byte, v T) []byte {
+			var c complex128
+			if bits == 32 {
+				c = complex128(as[complex64](v))
+			} else {
+				c = as[complex128](v)
+			}
+			b = appendJSONFloat(append(b, '['), real(c), bits)
+			b = appendJSONFloat(append(b, ','), imag(c), bits)
+			return append(b, ']')
+		}
+	}
+	format := formatFunc[T]()
+	return func(b []byte, v T) []byte {
+		return appendJSONNumber(b, format(v))
+	}
+}
+
+func appendJSONFloat(b []byte, f float64, bits int) []byte {
+	return appendJSONNumber(b, strconv.FormatFloat(f, 'g', -1, bits))
+}
+
+// appendJSONNumber appends a number formatted by strconv, and quotes it
+// if it is not finite as JSON has no representation of it.
+func appendJSONNumber(b []byte, s string) []byte {
+	if jsonNonFinite(s) {
+		return strconv.AppendQuote(b, s)
+	}
+	return append(b, s...)
+}
+
+func jsonNonFinite(s string) bool {
+	return s == "NaN" || s == "+Inf" || s == "-Inf"
+}
+
+// parseJSONFunc returns the function that parses a JSON value encoded by
+// appendJSONFunc.
+func parseJSONFunc[T Elem]() func(b []byte) (T, error) {
+	var v T
+	switch any(v).(type) {
+	case complex64, complex128:
+		bits := 4 * TypeSize[T]()
+		conv := complexFunc[T]()
+		return func(b []byte) (T, error) {
+			var parts []json.RawMessage
+			if err := json.Unmarshal(b, &parts); err != nil || len(parts) != 2 {
+				return v, fmt.Errorf("complex value %s is not an array of two numbers", b)
+			}
+			var f [2]float64
+			for k, p := range parts {
+				s, err := jsonNumber(p)
+				if err == nil {
+					f[k], err = strconv.ParseFloat(s, bits)
+				}
+				if err != nil {
+					return v, err
+				}
+			}
+			return conv(f[0], f[1]), nil
+		}
+	}
+	parse := parseFunc[T]()
+	return func(b []byte) (T, error) {
+		s, err := jsonNumber(b)
+		if err != nil {
+			return v, err
+		}
+		return parse(s)
+	}
+}
+
+// jsonNumber returns the number of a JSON value, which is either a
+// number or one of the strings of appendJSONNumber.
+func jsonNumber(b []byte) (string, error) {
+	b = bytes.TrimSpace(b)
+	if len(b) == 0 || b[0] != '"' {
+		var n json.Number
+		if err := json.Unmarshal(b, &n); err != nil || len(b) == 0 || b[0] == 'n' {
+			return "", fmt.Errorf("value %s is not a number", b)
+		}
+		return n.String(), nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil || !jsonNonFinite(s) {
+		return "", fmt.Errorf("value %s is not a number", b)
+	}
+	return s, nil
+}
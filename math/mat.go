@@ -4,71 +4,119 @@
 
 package math
 
-import (
-	"math"
-	"math/rand"
-)
-
-// Type defines all supported data types.
+// Type defines all supported arithmetic data types.
 type Type interface {
-	~uint8 | ~uint32 | ~int32 | ~float32
+	~int8 | ~uint8 | ~uint32 | ~int32 | ~int64 | ~float32 | ~float64 | ~complex64 | ~complex128
+}
+
+// Elem defines all supported element types of a matrix, which are the
+// arithmetic types of Type and the half-precision types of Half.
+//
+// A half-precision type only stores the bits of a number, hence a
+// matrix of it is a storage format: its elements can be accessed,
+// compared, encoded and converted by ToFloat32 and FromFloat32, and it
+// is multiplied by MulMixed. The arithmetic methods of Mat panic for
+// it, and the other operations only accept a Type.
+type Elem interface {
+	Type | Half
 }
 
 // TypeSize returns the corresponding type size.
-func TypeSize[T Type]() int {
+func TypeSize[T Elem]() int {
 	var v T
 	switch any(v).(type) {
-	case uint8:
+	case int8, uint8:
 		return 1
 	case int32:
 		return 4
 	case uint32:
 		return 4
+	case int64:
+		return 8
 	case float32:
 		return 4
+	case float64:
+		return 8
+	case complex64:
+		return 8
+	case complex128:
+		return 16
+	case Float16, BFloat16:
+		return 2
 	}
 	panic("unknown data size for type")
 }
 
 // Mat represents a WxH matrix.
-type Mat[T Type] struct {
-	Row  int
-	Col  int
-	Data []T
+//
+// Elements are stored in row-major order. Stride is the distance in Data
+// between two vertically adjacent elements; a zero Stride means the matrix
+// is dense and Stride equals Col. A matrix whose Stride is larger than Col
+// is a view into a larger matrix and its Data also covers elements that do
+// not belong to it, hence such a matrix should be accessed via Index.
+type Mat[T Elem] struct {
+	Row    int
+	Col    int
+	Stride int
+	Data   []T
 }
 
-// NewRandMat returns a random matrix.
-func NewRandMat[T Type](row, col int) Mat[T] {
+// NewRandMat returns a random matrix whose elements are drawn from the
+// distribution given by the options. By default, the elements of
+// floating-point and complex types are uniform in [0, 1), and the
+// elements of integer types are uniform integers in [0, 10). The
+// distribution must lie within the range of T.
+//
+// The elements are generated in parallel for large matrices, and the
+// result only depends on the seed, see WithSeed.
+func NewRandMat[T Type](row, col int, opts ...RandOption) Mat[T] {
 	m := Mat[T]{
 		Row:  row,
 		Col:  col,
 		Data: make([]T, row*col),
 	}
-
-	for i := range m.Data {
-		m.Data[i] = T(rand.Float64())
-	}
+	fill(m.Data, newRandConfig[T](opts))
 	return m
 }
 
-// Eq returns true if two matrices are equal.
+// Eq returns true if two matrices are equal within an absolute tolerance
+// of 1e-7. Use Compare for other tolerances and a report of the
+// differences.
 func (m Mat[T]) Eq(n Mat[T]) bool {
 	if m.Row != n.Row || m.Col != n.Col {
 		return false
 	}
 
-	approxEq := func(v1, v2 float64) bool { return math.Abs(v1-v2) <= 1e-7 }
-	for i := range m.Data {
-		if !approxEq(float64(m.Data[i]), float64(n.Data[i])) {
-			return false
+	dist := distFunc[T]()
+	approxEq := func(v1, v2 T) bool { return v1 == v2 || dist(v1, v2) <= 1e-7 }
+	for i := 0; i < m.Row; i++ {
+		for j := 0; j < m.Col; j++ {
+			if !approxEq(m.Get(i, j), n.Get(i, j)) {
+				return false
+			}
 		}
 	}
 	return true
 }
 
+// Dense returns true if the elements of the matrix are stored
+// contiguously in Data without any gaps between rows.
+func (m Mat[T]) Dense() bool {
+	return m.Stride == 0 || m.Stride == m.Col || m.Row <= 1
+}
+
+// RowStride returns the distance in Data between two vertically
+// adjacent elements.
+func (m Mat[T]) RowStride() int {
+	if m.Stride == 0 {
+		return m.Col
+	}
+	return m.Stride
+}
+
 // Index returns the element index of Data at (i, j)
 func (m Mat[T]) Index(i, j int) int {
-	return i*m.Col + j
+	return i*m.RowStride() + j
 }
 
 // Get gets the corresponding element at (i, j)
@@ -87,6 +135,9 @@ func (m Mat[T]) MulNaive(n Mat[T]) Mat[T] {
 	if m.Col != n.Row {
 		panic("math: mismatched matrix dimension")
 	}
+	if IsHalf[T]() {
+		panic("math: half-precision matrices are multiplied by MulMixed")
+	}
 
 	r := Mat[T]{
 		Row:  m.Row,
@@ -106,6 +157,10 @@ func (m Mat[T]) MulNaive(n Mat[T]) Mat[T] {
 	return r
 }
 
+// MulFunc is a matrix multiplication, such as Mat[T].Mul or gpu.Mul,
+// which algorithms accept to delegate their multiplications to.
+type MulFunc[T Type] func(m, n Mat[T]) Mat[T]
+
 // Mul applies matrix multiplication of two given matrix, and returns
 // the resulting matrix: r = m*n
 //
@@ -114,6 +169,9 @@ func (m Mat[T]) Mul(n Mat[T]) Mat[T] {
 	if m.Col != n.Row {
 		panic("math: mismatched matrix dimension")
 	}
+	if IsHalf[T]() {
+		panic("math: half-precision matrices are multiplied by MulMixed")
+	}
 
 	blockSize := 4
 
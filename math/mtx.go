@@ -0,0 +1,426 @@
+// Copyright 2023 Changkun Ou <changkun.de>. All rights reserved.
+// Use of this source code is governed by a MIT license that
+// can be found in the LICENSE file.
+
+package math
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ReadMTX reads a dense matrix from the Matrix Market exchange format.
+// Both the coordinate and the array formats are accepted, and the
+// symmetric, skew-symmetric and hermitian matrices are expanded. The
+// field of the file must be representable by T: a real field cannot be
+// read by integer types and a complex field only by complex types. A
+// malformed file results in a *ParseError, as does a coordinate file
+// whose dense matrix exceeds 1 GiB, which is read by ReadMTXCSR instead.
+func ReadMTX[T Elem](r io.Reader) (Mat[T], error) {
+	var (
+		row, col int
+		idx      []int // row-major indices of the entries
+		vals     []T
+	)
+	err := readMTX(r, func(h mtxHeader) error {
+		n := TypeSize[T]()
+		if !fits(h.row, h.col, n) {
+			return fmt.Errorf("%dx%d matrix is too large", h.row, h.col)
+		}
+		// The entries of an array file are as many as the elements,
+		// whereas a few entries of a coordinate file may describe a
+		// matrix of any size.
+		if h.format == "coordinate" && h.row*h.col*n > mtxMaxBytes {
+			return fmt.Errorf("%dx%d matrix exceeds %d bytes, use ReadMTXCSR", h.row, h.col, mtxMaxBytes)
+		}
+		row, col = h.row, h.col
+		idx, vals = make([]int, 0, minInt(h.nnz, mtxChunk)), make([]T, 0, minInt(h.nnz, mtxChunk))
+		return nil
+	}, func(i, j int, v T) {
+		idx, vals = append(idx, i*col+j), append(vals, v)
+	})
+	if err != nil {
+		return Mat[T]{}, err
+	}
+
+	// The matrix is only allocated once all entries are read, hence
+	// a truncated file fails before.
+	m := Mat[T]{Row: row, Col: col, Data: make([]T, row*col)}
+	for k, i := range idx {
+		m.Data[i] = vals[k]
+	}
+	return m, nil
+}
+
+// ReadMTXCSR reads a sparse matrix from the Matrix Market exchange
+// format as ReadMTX. The values of duplicated entries are summed. A
+// malformed file, or a matrix of 2^31 or more rows, results in a
+// *ParseError.
+func ReadMTXCSR[T Type](r io.Reader) (CSR[T], error) {
+	var (
+		row, col   int
+		rows, cols []int
+		vals       []T
+	)
+	err := readMTX(r, func(h mtxHeader) error {
+		if h.row > mtxMaxRows {
+			return fmt.Errorf("%dx%d matrix exceeds %d rows", h.row, h.col, mtxMaxRows)
+		}
+		row, col = h.row, h.col
+		n := minInt(h.nnz, mtxChunk)
+		rows, cols, vals = make([]int, 0, n), make([]int, 0, n), make([]T, 0, n)
+		return nil
+	}, func(i, j int, v T) {
+		rows, cols, vals = append(rows, i), append(cols, j), append(vals, v)
+	})
+	if err != nil {
+		return CSR[T]{}, err
+	}
+	return NewCSR(row, col, rows, cols, vals), nil
+}
+
+// WriteMTX writes a dense matrix in the Matrix Market array format.
+func WriteMTX[T Elem](w io.Writer, m Mat[T]) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "%%%%MatrixMarket matrix array %s general\n", mtxField[T]())
+	fmt.Fprintf(bw, "%d %d\n", m.Row, m.Col)
+	format := mtxFormatFunc[T]()
+	for j := 0; j < m.Col; j++ {
+		for i := 0; i < m.Row; i++ {
+			bw.WriteString(format(m.Get(i, j)))
+			bw.WriteByte('\n')
+		}
+	}
+	return bw.Flush()
+}
+
+// WriteMTXCSR writes a sparse matrix in the Matrix Market coordinate
+// format.
+func WriteMTXCSR[T Type](w io.Writer, s CSR[T]) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "%%%%MatrixMarket matrix coordinate %s general\n", mtxField[T]())
+	fmt.Fprintf(bw, "%d %d %d\n", s.Row, s.Col, s.NNZ())
+	format := mtxFormatFunc[T]()
+	for i := 0; i < s.Row; i++ {
+		for k := s.RowPtr[i]; k < s.RowPtr[i+1]; k++ {
+			fmt.Fprintf(bw, "%d %d %s\n", i+1, s.ColIdx[k]+1, format(s.Data[k]))
+		}
+	}
+	return bw.Flush()
+}
+
+const (
+	// mtxMaxBytes bounds the dense matrix that is allocated for the
+	// size line of a coordinate file.
+	mtxMaxBytes = 1 << 30
+	// mtxMaxRows bounds the row pointers that are allocated for the size
+	// line of a sparse matrix.
+	mtxMaxRows = 1<<31 - 1
+	// mtxChunk bounds the entries that are allocated before they are
+	// read, such that a corrupt number of entries does not exhaust the
+	// memory.
+	mtxChunk = 1 << 16
+)
+
+type mtxHeader struct {
+	format   string // coordinate or array
+	field    string // real, double, integer, complex or pattern
+	symmetry string // general, symmetric, skew-symmetric or hermitian
+	row, col int
+	nnz      int // number of entries in the file
+}
+
+// readMTX parses a Matrix Market file, calls size with the header once
+// the size line is read, and then calls set with every element, where
+// the elements implied by the symmetry are included. An error of size
+// stops the parsing.
+func readMTX[T Elem](r io.Reader, size func(h mtxHeader) error, set func(i, j int, v T)) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<20)
+	line := 1
+	fail := func(format string, args ...any) error {
+		return &ParseError{Format: "mtx", Line: line, Err: fmt.Errorf(format, args...)}
+	}
+	next := func() ([]string, bool) {
+		for sc.Scan() {
+			line++
+			s := strings.TrimSpace(sc.Text())
+			if s != "" && !strings.HasPrefix(s, "%") {
+				return strings.Fields(s), true
+			}
+		}
+		line++
+		return nil, false
+	}
+
+	if !sc.Scan() {
+		return fail("missing header")
+	}
+	h, err := parseMTXHeader[T](sc.Text())
+	if err != nil {
+		return fail("%v", err)
+	}
+
+	fields, ok := next()
+	if !ok {
+		return fail("missing size")
+	}
+	want := 3
+	if h.format == "array" {
+		want = 2
+	}
+	dims, err := parseInts(fields, want)
+	if err != nil || dims[0] < 0 || dims[1] < 0 || want == 3 && dims[2] < 0 {
+		return fail("malformed size %q", strings.Join(fields, " "))
+	}
+	h.row, h.col = dims[0], dims[1]
+	if h.symmetry != "general" && h.row != h.col {
+		return fail("%s matrix is not square", h.symmetry)
+	}
+	if h.format == "array" && !fits(h.row, h.col, 1) {
+		return fail("%dx%d matrix overflows", h.row, h.col)
+	}
+	// The entries of the array format are counted without overflow,
+	// since row*col fits.
+	switch {
+	case h.format == "coordinate":
+		h.nnz = dims[2]
+	case h.symmetry == "general":
+		h.nnz = h.row * h.col
+	case h.symmetry == "skew-symmetric":
+		h.nnz = (h.row*h.row - h.row) / 2
+	default:
+		h.nnz = (h.row*h.row-h.row)/2 + h.row
+	}
+	if err := size(h); err != nil {
+		return fail("%v", err)
+	}
+
+	value, mirror := mtxValueFunc[T](h.field), mtxMirrorFunc[T](h.symmetry)
+	nvals := 1
+	switch h.field {
+	case "pattern":
+		nvals = 0
+	case "complex":
+		nvals = 2
+	}
+
+	// The array format lists the columns of the lower triangle for
+	// the symmetric matrices, whereas the coordinate format names the
+	// positions explicitly.
+	i, j := 0, 0
+	if h.symmetry == "skew-symmetric" {
+		i = 1
+	}
+	for k := 0; k < h.nnz; k++ {
+		fields, ok := next()
+		if !ok {
+			return fail("missing entry %d of %d", k+1, h.nnz)
+		}
+		if h.format == "coordinate" {
+			if len(fields) != 2+nvals {
+				return fail("malformed entry %q", strings.Join(fields, " "))
+			}
+			idx, err := parseInts(fields[:2], 2)
+			if err != nil {
+				return fail("malformed entry %q", strings.Join(fields, " "))
+			}
+			i, j = idx[0]-1, idx[1]-1
+			fields = fields[2:]
+			if i < 0 || i >= h.row || j < 0 || j >= h.col {
+				return fail("index (%d, %d) out of range", i+1, j+1)
+			}
+		} else if len(fields) != nvals {
+			return fail("malformed entry %q", strings.Join(fields, " "))
+		}
+
+		switch {
+		case h.symmetry == "general":
+		case i < j, i == j && h.symmetry == "skew-symmetric":
+			return fail("entry (%d, %d) is not below the diagonal of a %s matrix", i+1, j+1, h.symmetry)
+		}
+		v, err := value(fields)
+		if err != nil {
+			return fail("malformed value: %v", err)
+		}
+		set(i, j, v)
+		if i != j && mirror != nil {
+			set(j, i, mirror(v))
+		}
+
+		if h.format == "array" {
+			if i++; i == h.row {
+				j++
+				i = j
+				if h.symmetry == "general" {
+					i = 0
+				} else if h.symmetry == "skew-symmetric" {
+					i++
+				}
+			}
+		}
+	}
+	if fields, ok := next(); ok {
+		return fail("unexpected entry %q", strings.Join(fields, " "))
+	}
+	if err := sc.Err(); err != nil {
+		return fail("%v", err)
+	}
+	return nil
+}
+
+func parseMTXHeader[T Elem](s string) (mtxHeader, error) {
+	f := strings.Fields(strings.ToLower(s))
+	if len(f) != 5 || f[0] != "%%matrixmarket" || f[1] != "matrix" {
+		return mtxHeader{}, fmt.Errorf("malformed header %q", s)
+	}
+	h := mtxHeader{format: f[2], field: f[3], symmetry: f[4]}
+	switch h.format {
+	case "coordinate", "array":
+	default:
+		return h, fmt.Errorf("unknown format %q", h.format)
+	}
+	switch h.symmetry {
+	case "general", "symmetric", "skew-symmetric", "hermitian":
+	default:
+		return h, fmt.Errorf("unknown symmetry %q", h.symmetry)
+	}
+
+	var v T
+	switch h.field {
+	case "integer":
+	case "pattern":
+		if h.format == "array" {
+			return h, errors.New("pattern field of an array")
+		}
+	case "real", "double":
+		if isInteger[T]() {
+			return h, fmt.Errorf("%s field cannot be read as %T", h.field, v)
+		}
+	case "complex":
+		if !IsComplex[T]() {
+			return h, fmt.Errorf("%s field cannot be read as %T", h.field, v)
+		}
+	default:
+		return h, fmt.Errorf("unknown field %q", h.field)
+	}
+	if h.symmetry == "hermitian" && h.field != "complex" {
+		return h, fmt.Errorf("hermitian matrix of %s field", h.field)
+	}
+	if h.symmetry == "skew-symmetric" && h.field == "pattern" {
+		return h, errors.New("skew-symmetric matrix of pattern field")
+	}
+	if h.symmetry == "skew-symmetric" && !mtxSigned[T]() {
+		return h, fmt.Errorf("skew-symmetric matrix cannot be read as %T", v)
+	}
+	return h, nil
+}
+
+// mtxValueFunc returns the function that parses the fields of an entry.
+func mtxValueFunc[T Elem](field string) func(fields []string) (T, error) {
+	switch field {
+	case "pattern":
+		one := fromFloat64[T](1)
+		return func([]string) (T, error) { return one, nil }
+	case "complex":
+		conv := complexFunc[T]()
+		return func(fields []string) (T, error) {
+			re, err := strconv.ParseFloat(fields[0], 64)
+			if err != nil {
+				return *new(T), err
+			}
+			im, err := strconv.ParseFloat(fields[1], 64)
+			return conv(re, im), err
+		}
+	}
+	parse := parseFunc[T]()
+	return func(fields []string) (T, error) { return parse(fields[0]) }
+}
+
+// mtxMirrorFunc returns the function that computes the element above the
+// diagonal from the element below, or nil for general matrices.
+func mtxMirrorFunc[T Elem](symmetry string) func(v T) T {
+	switch symmetry {
+	case "symmetric":
+		return func(v T) T { return v }
+	case "skew-symmetric":
+		return func(v T) T { return -v }
+	case "hermitian":
+		var v T
+		switch any(v).(type) {
+		case complex64:
+			return func(v T) T {
+				c := as[complex64](v)
+				return as[T](complex(real(c), -imag(c)))
+			}
+		case complex128:
+			return func(v T) T {
+				c := as[complex128](v)
+				return as[T](complex(real(c), -imag(c)))
+			}
+		}
+	}
+	return nil
+}
+
+// mtxSigned returns true if T can represent the negation of a value.
+func mtxSigned[T Elem]() bool {
+	var v T
+	switch any(v).(type) {
+	case uint8, uint32, Float16, BFloat16:
+		return false
+	}
+	return true
+}
+
+// mtxField returns the Matrix Market field of T.
+func mtxField[T Elem]() string {
+	switch {
+	case isInteger[T]():
+		return "integer"
+	case IsComplex[T]():
+		return "complex"
+	}
+	return "real"
+}
+
+// mtxFormatFunc returns the function that formats a value, where the
+// real and the imaginary parts of a complex value are separated by a
+// space.
+func mtxFormatFunc[T Elem]() func(v T) string {
+	var v T
+	switch any(v).(type) {
+	case complex64:
+		return func(v T) string {
+			c := as[complex64](v)
+			return strconv.FormatFloat(float64(real(c)), 'g', -1, 32) + " " +
+				strconv.FormatFloat(float64(imag(c)), 'g', -1, 32)
+		}
+	case complex128:
+		return func(v T) string {
+			c := as[complex128](v)
+			return strconv.FormatFloat(real(c), 'g', -1, 64) + " " +
+				strconv.FormatFloat(imag(c), 'g', -1, 64)
+		}
+	}
+	return formatFunc[T]()
+}
+
+func parseInts(fields []string, n int) ([]int, error) {
+	if len(fields) != n {
+		return nil, errors.New("mismatched number of fields")
+	}
+	r := make([]int, n)
+	for i, f := range fields {
+		v, err := strconv.Atoi(f)
+		if err != nil {
+			return nil, err
+		}
+		r[i] = v
+	}
+	return r, nil
+}
@@ -0,0 +1,403 @@
+// Copyright 2023 Changkun Ou <changkun.de>. All rights reserved.
+// Use of this source code is governed by a MIT license that
+// can be found in the LICENSE file.
+
+package math
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+// ErrNPY is returned if a file is not a valid NumPy .npy file, or if it
+// cannot be represented by a matrix of the requested type.
+var ErrNPY = errors.New("math: invalid npy file")
+
+// NPYOption configures the layout of the data written by WriteNPY.
+type NPYOption func(c *npyConfig)
+
+type npyConfig struct {
+	fortran bool
+	order   binary.ByteOrder
+}
+
+// NPYFortranOrder writes the elements in column-major order.
+func NPYFortranOrder() NPYOption {
+	return func(c *npyConfig) { c.fortran = true }
+}
+
+// NPYByteOrder writes the elements in the given byte order, which is
+// little-endian by default. An order such as binary.NativeEndian is
+// written as the little or the big endian order it is equivalent to.
+func NPYByteOrder(order binary.ByteOrder) NPYOption {
+	if order.Uint16([]byte{1, 0}) == 1 {
+		order = binary.LittleEndian
+	} else {
+		order = binary.BigEndian
+	}
+	return func(c *npyConfig) { c.order = order }
+}
+
+// npyMagic is the prefix of every .npy file.
+const npyMagic = "\x93NUMPY"
+
+// npyMaxHeader bounds the length of a header, as numpy.load does by
+// default, such that a corrupt length does not exhaust the memory.
+const npyMaxHeader = 10000
+
+// WriteNPY writes a matrix in the NumPy .npy format version 1.0, whose
+// shape is (Row, Col). BFloat16 has no NumPy dtype and cannot be written.
+func WriteNPY[T Elem](w io.Writer, m Mat[T], opts ...NPYOption) error {
+	c := npyConfig{order: binary.LittleEndian}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	descr, err := npyDescr[T](c.order)
+	if err != nil {
+		return err
+	}
+
+	// The header is padded with spaces and terminated by a newline, such
+	// that the data is aligned to 64 bytes.
+	header := fmt.Sprintf("{'descr': '%s', 'fortran_order': %s, 'shape': (%d, %d), }",
+		descr, map[bool]string{false: "False", true: "True"}[c.fortran], m.Row, m.Col)
+	pad := 64 - (len(npyMagic)+4+len(header)+1)%64
+	header += strings.Repeat(" ", pad%64) + "\n"
+
+	buf := bytes.NewBufferString(npyMagic)
+	buf.Write([]byte{1, 0})
+	binary.Write(buf, binary.LittleEndian, uint16(len(header)))
+	buf.WriteString(header)
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return err
+	}
+
+	v := m.View()
+	if c.fortran {
+		v = v.T()
+	}
+	row, col := v.Dims()
+	line := make([]T, col)
+	for i := 0; i < row; i++ {
+		for j := range line {
+			line[j] = v.Get(i, j)
+		}
+		if _, err := w.Write(encode(line, c.order)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadNPY reads a matrix from the NumPy .npy format. The dtype of the
+// file must be the type T in any byte order, and the array must have at
+// most two dimensions: a scalar is read as a 1x1 matrix and an array of
+// shape (n,) as a 1xn matrix. Arrays in both C and Fortran order are
+// read into a dense matrix.
+func ReadNPY[T Elem](r io.Reader) (Mat[T], error) {
+	h, err := readNPYHeader(r)
+	if err != nil {
+		return Mat[T]{}, err
+	}
+	order, err := npyOrder[T](h.descr)
+	if err != nil {
+		return Mat[T]{}, err
+	}
+
+	var row, col int
+	switch len(h.shape) {
+	case 0:
+		row, col = 1, 1
+	case 1:
+		row, col = 1, h.shape[0]
+	case 2:
+		row, col = h.shape[0], h.shape[1]
+	default:
+		return Mat[T]{}, fmt.Errorf("%w: %d dimensions cannot be read as a matrix", ErrNPY, len(h.shape))
+	}
+
+	if !fits(row, col, TypeSize[T]()) {
+		return Mat[T]{}, fmt.Errorf("%w: shape (%d, %d) is too large", ErrNPY, row, col)
+	}
+	data, err := readElems[T](r, row*col)
+	if err != nil {
+		return Mat[T]{}, fmt.Errorf("%w: %v", ErrNPY, err)
+	}
+	m := Mat[T]{Row: row, Col: col, Data: data}
+	if order != nativeOrder() {
+		swap[T](bytesOf(data))
+	}
+	if h.fortran {
+		m = Mat[T]{Row: col, Col: row, Data: m.Data}.View().T().Clone()
+	}
+	return m, nil
+}
+
+// maxInt is the largest value of int.
+const maxInt = int(^uint(0) >> 1)
+
+// fits reports whether the elements of a matrix of the given shape and
+// element size can be addressed, i.e. row*col*size does not overflow.
+func fits(row, col, size int) bool {
+	return row >= 0 && col >= 0 && (col == 0 || row <= maxInt/size/col)
+}
+
+// readElems reads n elements of type T in the native byte order. The
+// elements are allocated in chunks as they are read, hence a corrupt
+// size results in an error at the end of r rather than exhausting the
+// memory up front.
+func readElems[T Elem](r io.Reader, n int) ([]T, error) {
+	const chunk = 1 << 16
+	data := make([]T, 0, minInt(n, chunk))
+	for len(data) < n {
+		k := minInt(n-len(data), chunk)
+		if cap(data)-len(data) < k {
+			grown := make([]T, len(data), minInt(2*cap(data)+k, n))
+			copy(grown, data)
+			data = grown
+		}
+		data = data[:len(data)+k]
+		if _, err := io.ReadFull(r, bytesOf(data[len(data)-k:])); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// WriteNPZ writes matrices to an uncompressed NumPy .npz archive, where
+// every matrix is stored as a .npy file named by its key.
+func WriteNPZ[T Elem](w io.Writer, ms map[string]Mat[T], opts ...NPYOption) error {
+	names := make([]string, 0, len(ms))
+	for name := range ms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	z := zip.NewWriter(w)
+	for _, name := range names {
+		f, err := z.CreateHeader(&zip.FileHeader{Name: name + ".npy", Method: zip.Store})
+		if err != nil {
+			return err
+		}
+		if err := WriteNPY(f, ms[name], opts...); err != nil {
+			return err
+		}
+	}
+	return z.Close()
+}
+
+// ReadNPZ reads all matrices of a NumPy .npz archive, which may be
+// compressed, and returns them by their names without the .npy suffix.
+// All arrays must be readable by ReadNPY as matrices of type T.
+func ReadNPZ[T Elem](r io.ReaderAt, size int64) (map[string]Mat[T], error) {
+	z, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	ms := make(map[string]Mat[T], len(z.File))
+	for _, f := range z.File {
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		m, err := ReadNPY[T](rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Name, err)
+		}
+		ms[strings.TrimSuffix(f.Name, ".npy")] = m
+	}
+	return ms, nil
+}
+
+type npyHeader struct {
+	descr   string
+	fortran bool
+	shape   []int
+}
+
+var (
+	npyDescrRe   = regexp.MustCompile(`'descr'\s*:\s*'([^']*)'`)
+	npyFortranRe = regexp.MustCompile(`'fortran_order'\s*:\s*(True|False)`)
+	npyShapeRe   = regexp.MustCompile(`'shape'\s*:\s*\(([^)]*)\)`)
+)
+
+func readNPYHeader(r io.Reader) (npyHeader, error) {
+	pre := make([]byte, len(npyMagic)+2)
+	if _, err := io.ReadFull(r, pre); err != nil {
+		return npyHeader{}, fmt.Errorf("%w: %v", ErrNPY, err)
+	}
+	if string(pre[:len(npyMagic)]) != npyMagic {
+		return npyHeader{}, fmt.Errorf("%w: missing magic string", ErrNPY)
+	}
+
+	// Version 1.0 stores the header length in 2 bytes, whereas the
+	// versions 2.0 and 3.0 store it in 4 bytes.
+	var n uint32
+	switch major := pre[len(npyMagic)]; major {
+	case 1:
+		var n16 uint16
+		if err := binary.Read(r, binary.LittleEndian, &n16); err != nil {
+			return npyHeader{}, fmt.Errorf("%w: %v", ErrNPY, err)
+		}
+		n = uint32(n16)
+	case 2, 3:
+		if err := binary.Read(r, binary.LittleEndian, &n); err != nil {
+			return npyHeader{}, fmt.Errorf("%w: %v", ErrNPY, err)
+		}
+	default:
+		return npyHeader{}, fmt.Errorf("%w: unsupported version %d", ErrNPY, major)
+	}
+	if n > npyMaxHeader {
+		return npyHeader{}, fmt.Errorf("%w: header of %d bytes exceeds %d", ErrNPY, n, npyMaxHeader)
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return npyHeader{}, fmt.Errorf("%w: %v", ErrNPY, err)
+	}
+
+	header := string(b)
+	descr := npyDescrRe.FindStringSubmatch(header)
+	fortran := npyFortranRe.FindStringSubmatch(header)
+	shape := npyShapeRe.FindStringSubmatch(header)
+	if descr == nil || fortran == nil || shape == nil {
+		return npyHeader{}, fmt.Errorf("%w: malformed header %q", ErrNPY, strings.TrimSpace(header))
+	}
+
+	h := npyHeader{descr: descr[1], fortran: fortran[1] == "True"}
+	for _, s := range strings.Split(shape[1], ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		d, err := strconv.Atoi(s)
+		if err != nil || d < 0 {
+			return npyHeader{}, fmt.Errorf("%w: malformed shape (%s)", ErrNPY, shape[1])
+		}
+		h.shape = append(h.shape, d)
+	}
+	return h, nil
+}
+
+// npyType returns the NumPy type code of T without the byte order.
+func npyType[T Elem]() (string, error) {
+	var v T
+	switch any(v).(type) {
+	case int8:
+		return "i1", nil
+	case uint8:
+		return "u1", nil
+	case int32:
+		return "i4", nil
+	case uint32:
+		return "u4", nil
+	case int64:
+		return "i8", nil
+	case Float16:
+		return "f2", nil
+	case float32:
+		return "f4", nil
+	case float64:
+		return "f8", nil
+	case complex64:
+		return "c8", nil
+	case complex128:
+		return "c16", nil
+	}
+	return "", fmt.Errorf("%w: %T has no NumPy dtype", ErrNPY, v)
+}
+
+// npyDescr returns the NumPy dtype of T in the given byte order. Single
+// bytes have no byte order.
+func npyDescr[T Elem](order binary.ByteOrder) (string, error) {
+	typ, err := npyType[T]()
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case TypeSize[T]() == 1:
+		return "|" + typ, nil
+	case order == binary.BigEndian:
+		return ">" + typ, nil
+	}
+	return "<" + typ, nil
+}
+
+// npyOrder checks that the NumPy dtype descr is the type T, and returns
+// its byte order.
+func npyOrder[T Elem](descr string) (binary.ByteOrder, error) {
+	typ, err := npyType[T]()
+	if err != nil {
+		return nil, err
+	}
+	if len(descr) < 2 || descr[1:] != typ {
+		var v T
+		return nil, fmt.Errorf("%w: dtype %s cannot be read as %T", ErrNPY, descr, v)
+	}
+	switch descr[0] {
+	case '<':
+		return binary.LittleEndian, nil
+	case '>':
+		return binary.BigEndian, nil
+	case '|', '=':
+		return nativeOrder(), nil
+	}
+	return nil, fmt.Errorf("%w: malformed dtype %s", ErrNPY, descr)
+}
+
+// encode returns the bytes of the elements in the given byte order.
+func encode[T Elem](v []T, order binary.ByteOrder) []byte {
+	b := bytesOf(v)
+	if order != nativeOrder() {
+		b = append([]byte(nil), b...)
+		swap[T](b)
+	}
+	return b
+}
+
+// bytesOf returns the memory of the elements as bytes.
+func bytesOf[T Elem](v []T) []byte {
+	if len(v) == 0 {
+		return nil
+	}
+	return unsafe.Slice((*byte)(unsafe.Pointer(&v[0])), len(v)*TypeSize[T]())
+}
+
+// swap reverses the byte order of the elements in b. The real and the
+// imaginary parts of a complex number are swapped separately.
+func swap[T Elem](b []byte) {
+	n := TypeSize[T]()
+	if IsComplex[T]() {
+		n /= 2
+	}
+	for k := 0; k < len(b); k += n {
+		for i, j := k, k+n-1; i < j; i, j = i+1, j-1 {
+			b[i], b[j] = b[j], b[i]
+		}
+	}
+}
+
+// nativeOrder returns the byte order of the host.
+func nativeOrder() binary.ByteOrder {
+	x := uint16(1)
+	if *(*byte)(unsafe.Pointer(&x)) == 1 {
+		return binary.LittleEndian
+	}
+	return binary.BigEndian
+}
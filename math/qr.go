@@ -0,0 +1,175 @@
+// Copyright 2023 Changkun Ou <changkun.de>. All rights reserved.
+// Use of this source code is governed by a MIT license that
+// can be found in the LICENSE file.
+
+package math
+
+// QR is the QR decomposition of a MxN matrix A with M >= N, such that
+// A = Q*R where Q is a MxN matrix with orthonormal columns and R is a
+// NxN upper triangular matrix. The decomposition is computed using
+// Householder reflections.
+type QR[T Float] struct {
+	qr    Mat[T] // Householder vectors on and below the diagonal, R above it
+	rdiag []T    // diagonal of R
+	rank  int
+}
+
+// FactorQR computes the QR decomposition of a matrix a, which must have
+// at least as many rows as columns.
+func FactorQR[T Float](a Mat[T]) *QR[T] {
+	m, n := a.Row, a.Col
+	if m < n {
+		panic("math: matrix has fewer rows than columns")
+	}
+
+	f := &QR[T]{qr: a.Clone(), rdiag: make([]T, n)}
+	qr := f.qr
+	for k := 0; k < n; k++ {
+		var nrm T
+		for i := k; i < m; i++ {
+			nrm = hypot(nrm, qr.Get(i, k))
+		}
+
+		if nrm != 0 {
+			// Form the k-th Householder vector and apply the
+			// reflection to the remaining columns.
+			if qr.Get(k, k) < 0 {
+				nrm = -nrm
+			}
+			for i := k; i < m; i++ {
+				qr.Set(i, k, qr.Get(i, k)/nrm)
+			}
+			qr.Set(k, k, qr.Get(k, k)+1)
+
+			for j := k + 1; j < n; j++ {
+				var s T
+				for i := k; i < m; i++ {
+					s += qr.Get(i, k) * qr.Get(i, j)
+				}
+				s = -s / qr.Get(k, k)
+				for i := k; i < m; i++ {
+					qr.Set(i, j, qr.Get(i, j)+s*qr.Get(i, k))
+				}
+			}
+		}
+		f.rdiag[k] = -nrm
+	}
+
+	// A diagonal element of R below this tolerance is treated as zero.
+	var max T
+	for _, v := range f.rdiag {
+		if abs(v) > max {
+			max = abs(v)
+		}
+	}
+	tol := T(m) * epsilon[T]() * max
+	for _, v := range f.rdiag {
+		if abs(v) > tol {
+			f.rank++
+		}
+	}
+	return f
+}
+
+// Rank returns the numerical rank of the factorized matrix, which is the
+// number of diagonal elements of R that are non-zero to working precision.
+// As the decomposition does not pivot columns, the rank is only an
+// estimate if the matrix is rank deficient.
+func (f *QR[T]) Rank() int { return f.rank }
+
+// FullRank returns true if the factorized matrix has full column rank.
+func (f *QR[T]) FullRank() bool { return f.rank == f.qr.Col }
+
+// Q returns the MxN orthonormal factor.
+func (f *QR[T]) Q() Mat[T] {
+	m, n := f.qr.Row, f.qr.Col
+	q := Mat[T]{Row: m, Col: n, Data: make([]T, m*n)}
+	for k := n - 1; k >= 0; k-- {
+		q.Set(k, k, 1)
+		for j := k; j < n; j++ {
+			if f.qr.Get(k, k) == 0 {
+				continue
+			}
+			var s T
+			for i := k; i < m; i++ {
+				s += f.qr.Get(i, k) * q.Get(i, j)
+			}
+			s = -s / f.qr.Get(k, k)
+			for i := k; i < m; i++ {
+				q.Set(i, j, q.Get(i, j)+s*f.qr.Get(i, k))
+			}
+		}
+	}
+	return q
+}
+
+// R returns the NxN upper triangular factor.
+func (f *QR[T]) R() Mat[T] {
+	n := f.qr.Col
+	r := Mat[T]{Row: n, Col: n, Data: make([]T, n*n)}
+	for i := 0; i < n; i++ {
+		r.Set(i, i, f.rdiag[i])
+		for j := i + 1; j < n; j++ {
+			r.Set(i, j, f.qr.Get(i, j))
+		}
+	}
+	return r
+}
+
+// Solve solves the least-squares problem min ||A*X - B|| for X, where
+// every column of b is a right-hand side. It also returns the residuals
+// as a 1xK matrix that holds the squared residual norm of each of the
+// K columns of b. It returns ErrRankDeficient if A does not have full
+// column rank.
+func (f *QR[T]) Solve(b Mat[T]) (x, residuals Mat[T], err error) {
+	m, n := f.qr.Row, f.qr.Col
+	if b.Row != m {
+		panic("math: mismatched matrix dimension")
+	}
+	if !f.FullRank() {
+		return Mat[T]{}, Mat[T]{}, ErrRankDeficient
+	}
+
+	// Compute Q'*B, whose trailing M-N rows are the residuals.
+	y := b.Clone()
+	for k := 0; k < n; k++ {
+		for j := 0; j < y.Col; j++ {
+			var s T
+			for i := k; i < m; i++ {
+				s += f.qr.Get(i, k) * y.Get(i, j)
+			}
+			s = -s / f.qr.Get(k, k)
+			for i := k; i < m; i++ {
+				y.Set(i, j, y.Get(i, j)+s*f.qr.Get(i, k))
+			}
+		}
+	}
+	residuals = Mat[T]{Row: 1, Col: y.Col, Data: make([]T, y.Col)}
+	for j := 0; j < y.Col; j++ {
+		var s T
+		for i := n; i < m; i++ {
+			s += y.Get(i, j) * y.Get(i, j)
+		}
+		residuals.Data[j] = s
+	}
+
+	// Solve R*X = Q'*B by back substitution.
+	x = y.Slice(0, n, 0, y.Col)
+	for j := 0; j < x.Col; j++ {
+		for k := n - 1; k >= 0; k-- {
+			x.Set(k, j, x.Get(k, j)/f.rdiag[k])
+			for i := 0; i < k; i++ {
+				x.Set(i, j, x.Get(i, j)-x.Get(k, j)*f.qr.Get(i, k))
+			}
+		}
+	}
+	return x.Clone(), residuals, nil
+}
+
+// LeastSquares solves the overdetermined linear system a*x = b in the
+// least-squares sense using the QR decomposition of a, and returns the
+// solution as well as the squared residual norm of each column of b.
+// It returns ErrRankDeficient if a does not have full column rank.
+func LeastSquares[T Float](a, b Mat[T]) (x, residuals Mat[T], err error) {
+	return FactorQR(a).Solve(b)
+}
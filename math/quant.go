@@ -0,0 +1,142 @@
+// Copyright 2023 Changkun Ou <changkun.de>. All rights reserved.
+// Use of this source code is governed by a MIT license that
+// can be found in the LICENSE file.
+
+package math
+
+import "math"
+
+// Q8 defines the element types of quantized matrices.
+type Q8 interface {
+	int8 | uint8
+}
+
+// QMat is an 8-bit quantized matrix. An element q represents the real
+// value Scale*(q-ZeroPoint), hence the real zero is represented exactly
+// by ZeroPoint.
+//
+// The quantized elements are not embedded but held by the field Mat,
+// since the arithmetic of Mat on them would ignore the scale and the
+// zero point and overflow. A QMat is multiplied by MulQuantized.
+type QMat[T Q8] struct {
+	Mat       Mat[T] // quantized elements
+	Scale     float32
+	ZeroPoint int32
+}
+
+// QuantizeFrom quantizes a float32 matrix with the affine mapping that
+// covers the range of its elements and the zero.
+func QuantizeFrom[T Q8](m Mat[float32]) QMat[T] {
+	var lo, hi float32
+	for i := 0; i < m.Row; i++ {
+		for j := 0; j < m.Col; j++ {
+			v := m.Get(i, j)
+			if v < lo {
+				lo = v
+			}
+			if v > hi {
+				hi = v
+			}
+		}
+	}
+
+	qlo, qhi := QuantRange[T]()
+	scale := (hi - lo) / float32(qhi-qlo)
+	if scale == 0 {
+		scale = 1
+	}
+	zero := saturate(float64(float32(qlo)-lo/scale), 0, qlo, qhi)
+	return Quantize[T](m, scale, zero)
+}
+
+// Quantize quantizes a float32 matrix with the given scale and zero
+// point. Elements beyond the range of T are saturated.
+func Quantize[T Q8](m Mat[float32], scale float32, zero int32) QMat[T] {
+	qlo, qhi := QuantRange[T]()
+	q := QMat[T]{
+		Mat:       Mat[T]{Row: m.Row, Col: m.Col, Data: make([]T, m.Row*m.Col)},
+		Scale:     scale,
+		ZeroPoint: zero,
+	}
+	for i := 0; i < m.Row; i++ {
+		for j := 0; j < m.Col; j++ {
+			q.Mat.Data[i*m.Col+j] = T(saturate(float64(m.Get(i, j)/scale), zero, qlo, qhi))
+		}
+	}
+	return q
+}
+
+// Dequantize returns the real values of a quantized matrix.
+func (q QMat[T]) Dequantize() Mat[float32] {
+	m := q.Mat
+	r := Mat[float32]{Row: m.Row, Col: m.Col, Data: make([]float32, m.Row*m.Col)}
+	for i := 0; i < m.Row; i++ {
+		for j := 0; j < m.Col; j++ {
+			r.Data[i*m.Col+j] = q.Scale * float32(int32(m.Get(i, j))-q.ZeroPoint)
+		}
+	}
+	return r
+}
+
+// MulQuantized multiplies two quantized matrices and requantizes the
+// result with the given scale and zero point: r = m*n
+//
+// The products of the elements are accumulated in int32, which cannot
+// overflow for up to 2^15 columns of m. The rows of the result are
+// computed in parallel.
+func MulQuantized[T Q8](m, n QMat[T], scale float32, zero int32) QMat[T] {
+	a, b := m.Mat, n.Mat
+	if a.Col != b.Row {
+		panic("math: mismatched matrix dimension")
+	}
+
+	qlo, qhi := QuantRange[T]()
+	mult := m.Scale * n.Scale / scale
+	r := QMat[T]{
+		Mat:       Mat[T]{Row: a.Row, Col: b.Col, Data: make([]T, a.Row*b.Col)},
+		Scale:     scale,
+		ZeroPoint: zero,
+	}
+	parallel(a.Row, func(i int) {
+		for j := 0; j < b.Col; j++ {
+			var acc int32
+			for k := 0; k < a.Col; k++ {
+				acc += (int32(a.Get(i, k)) - m.ZeroPoint) * (int32(b.Get(k, j)) - n.ZeroPoint)
+			}
+			r.Mat.Data[i*b.Col+j] = T(Requantize(acc, mult, zero, qlo, qhi))
+		}
+	})
+	return r
+}
+
+// Requantize scales an int32 accumulator by mult, rounding ties to even,
+// shifts it by the zero point and saturates it to [lo, hi]. The product
+// is computed in float32, such that all backends agree on the result.
+func Requantize(acc int32, mult float32, zero, lo, hi int32) int32 {
+	return saturate(float64(float32(acc)*mult), zero, lo, hi)
+}
+
+// QuantRange returns the range of the quantized values of type T.
+func QuantRange[T Q8]() (lo, hi int32) {
+	var v T
+	switch any(v).(type) {
+	case int8:
+		return math.MinInt8, math.MaxInt8
+	case uint8:
+		return 0, math.MaxUint8
+	}
+	panic("unknown quantization range for type")
+}
+
+// saturate rounds v to the nearest integer, ties to even, shifts it by
+// the zero point and clamps it to [lo, hi].
+func saturate(v float64, zero, lo, hi int32) int32 {
+	v = math.RoundToEven(v) + float64(zero)
+	if v < float64(lo) {
+		return lo
+	}
+	if v > float64(hi) {
+		return hi
+	}
+	return int32(v)
+}
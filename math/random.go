@@ -0,0 +1,148 @@
+// Copyright 2023 Changkun Ou <changkun.de>. All rights reserved.
+// Use of this source code is governed by a MIT license that
+// can be found in the LICENSE file.
+
+package math
+
+import (
+	"math"
+	"math/rand"
+)
+
+// RandOption configures the random generation of NewRandMat.
+type RandOption func(c *randConfig)
+
+type randConfig struct {
+	seed  int64
+	dist  func(r *rand.Rand) float64
+	round bool // rounds the values for integer types
+}
+
+// WithSeed makes the random generation reproducible with the given seed.
+// Without a seed or a source, every matrix is generated from a new seed.
+func WithSeed(seed int64) RandOption {
+	return func(c *randConfig) { c.seed = seed }
+}
+
+// WithSource derives the seed from the given source. The source is
+// only used once per matrix, hence a seeded source generates the same
+// sequence of matrices in every run.
+func WithSource(src rand.Source) RandOption {
+	return func(c *randConfig) { c.seed = src.Int63() }
+}
+
+// Uniform draws the elements uniformly from [lo, hi).
+func Uniform(lo, hi float64) RandOption {
+	return func(c *randConfig) {
+		c.dist = func(r *rand.Rand) float64 { return lo + (hi-lo)*r.Float64() }
+		c.round = false
+	}
+}
+
+// Normal draws the elements from the normal distribution with the given
+// mean and standard deviation. Integer types round the values to the
+// nearest integer.
+func Normal(mean, std float64) RandOption {
+	return func(c *randConfig) {
+		c.dist = func(r *rand.Rand) float64 { return mean + std*r.NormFloat64() }
+		c.round = true
+	}
+}
+
+// IntRange draws the elements uniformly from the integers in [lo, hi),
+// which must be exactly representable by a float64.
+func IntRange(lo, hi int64) RandOption {
+	if hi <= lo {
+		panic("math: invalid integer range")
+	}
+	return func(c *randConfig) {
+		c.dist = func(r *rand.Rand) float64 { return float64(lo + r.Int63n(hi-lo)) }
+	}
+}
+
+// Zeros returns a matrix whose elements are all zero.
+func Zeros[T Elem](row, col int) Mat[T] {
+	return Mat[T]{Row: row, Col: col, Data: make([]T, row*col)}
+}
+
+// Ones returns a matrix whose elements are all one.
+func Ones[T Type](row, col int) Mat[T] {
+	m := Zeros[T](row, col)
+	one := fromFloat64[T](1)
+	for i := range m.Data {
+		m.Data[i] = one
+	}
+	return m
+}
+
+// Identity returns the nxn identity matrix.
+func Identity[T Type](n int) Mat[T] {
+	m := Zeros[T](n, n)
+	one := fromFloat64[T](1)
+	for i := 0; i < n; i++ {
+		m.Data[i*n+i] = one
+	}
+	return m
+}
+
+// Diag returns the square matrix whose diagonal elements are v.
+func Diag[T Type](v ...T) Mat[T] {
+	n := len(v)
+	m := Zeros[T](n, n)
+	for i := 0; i < n; i++ {
+		m.Data[i*n+i] = v[i]
+	}
+	return m
+}
+
+// randChunk is the number of elements generated from the same seed. The
+// chunks are independent of the number of CPUs, which keeps the parallel
+// generation deterministic.
+const randChunk = 1 << 14
+
+func newRandConfig[T Type](opts []RandOption) randConfig {
+	c := randConfig{seed: rand.Int63()}
+	if isInteger[T]() {
+		IntRange(0, 10)(&c)
+	} else {
+		Uniform(0, 1)(&c)
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	c.round = c.round && isInteger[T]()
+	return c
+}
+
+// fill draws the elements of data from the configured distribution.
+func fill[T Type](data []T, c randConfig) {
+	conv, cplx := complexFunc[T](), IsComplex[T]()
+	n := (len(data) + randChunk - 1) / randChunk
+	parallel(n, func(k int) {
+		r := rand.New(rand.NewSource(splitmix(c.seed + int64(k))))
+		end := (k + 1) * randChunk
+		if end > len(data) {
+			end = len(data)
+		}
+		for i := k * randChunk; i < end; i++ {
+			var im float64
+			re := c.dist(r)
+			if cplx {
+				im = c.dist(r)
+			}
+			if c.round {
+				re = math.Round(re)
+			}
+			data[i] = conv(re, im)
+		}
+	})
+}
+
+// splitmix scrambles the seeds of adjacent chunks, which are otherwise
+// correlated, see https://prng.di.unimi.it/splitmix64.c.
+func splitmix(x int64) int64 {
+	z := uint64(x) + 0x9e3779b97f4a7c15
+	z = (z ^ z>>30) * 0xbf58476d1ce4e5b9
+	z = (z ^ z>>27) * 0x94d049bb133111eb
+	return int64(z ^ z>>31)
+}
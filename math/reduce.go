@@ -0,0 +1,129 @@
+// Copyright 2023 Changkun Ou <changkun.de>. All rights reserved.
+// Use of this source code is governed by a MIT license that
+// can be found in the LICENSE file.
+
+package math
+
+// Reductions along an axis follow the NumPy convention: axis 0 reduces
+// every column over all rows and results in a 1xCol matrix, whereas
+// axis 1 reduces every row over all columns and results in a Rowx1 matrix.
+
+// Sum returns the sum of all elements.
+func (m Mat[T]) Sum() T {
+	arithmetic[T]()
+	var sum T
+	for i := 0; i < m.Row; i++ {
+		for j := 0; j < m.Col; j++ {
+			sum += m.Get(i, j)
+		}
+	}
+	return sum
+}
+
+// Mean returns the arithmetic mean of all elements. For integer types
+// the result is truncated.
+func (m Mat[T]) Mean() T {
+	return m.Sum() / fromFloat64[T](float64(m.Row*m.Col))
+}
+
+// The following comparisons panic for complex types, which are not
+// ordered. Unlike the arithmetic methods, they compare the values of
+// half-precision types.
+
+// Max returns the maximum element.
+func (m Mat[T]) Max() T {
+	i, j := m.ArgMax()
+	return m.Get(i, j)
+}
+
+// Min returns the minimum element.
+func (m Mat[T]) Min() T {
+	i, j := m.argBest(lessFunc[T]())
+	return m.Get(i, j)
+}
+
+// ArgMax returns the position of the first maximum element.
+func (m Mat[T]) ArgMax() (i, j int) {
+	less := lessFunc[T]()
+	return m.argBest(func(a, b T) bool { return less(b, a) })
+}
+
+// SumAxis returns the sums along the given axis.
+func (m Mat[T]) SumAxis(axis int) Mat[T] {
+	return m.reduceAxis(axis, func(v Mat[T]) T { return v.Sum() })
+}
+
+// MeanAxis returns the arithmetic means along the given axis.
+func (m Mat[T]) MeanAxis(axis int) Mat[T] {
+	return m.reduceAxis(axis, func(v Mat[T]) T { return v.Mean() })
+}
+
+// MaxAxis returns the maximum elements along the given axis.
+func (m Mat[T]) MaxAxis(axis int) Mat[T] {
+	return m.reduceAxis(axis, func(v Mat[T]) T { return v.Max() })
+}
+
+// MinAxis returns the minimum elements along the given axis.
+func (m Mat[T]) MinAxis(axis int) Mat[T] {
+	return m.reduceAxis(axis, func(v Mat[T]) T { return v.Min() })
+}
+
+// ArgMaxAxis returns the positions of the first maximum elements along
+// the given axis. For axis 0 the positions are row indices, one for each
+// column, and for axis 1 they are column indices, one for each row.
+func (m Mat[T]) ArgMaxAxis(axis int) []int {
+	r := m.lines(axis)
+	idx := make([]int, len(r))
+	for k, v := range r {
+		i, j := v.ArgMax()
+		idx[k] = i + j
+	}
+	return idx
+}
+
+func (m Mat[T]) argBest(better func(a, b T) bool) (int, int) {
+	if m.Row == 0 || m.Col == 0 {
+		panic("math: reduction of an empty matrix")
+	}
+
+	bi, bj := 0, 0
+	best := m.Get(0, 0)
+	for i := 0; i < m.Row; i++ {
+		for j := 0; j < m.Col; j++ {
+			if v := m.Get(i, j); better(v, best) {
+				best, bi, bj = v, i, j
+			}
+		}
+	}
+	return bi, bj
+}
+
+// lines returns the columns of m for axis 0 and the rows of m for axis 1.
+func (m Mat[T]) lines(axis int) []Mat[T] {
+	var r []Mat[T]
+	switch axis {
+	case 0:
+		for j := 0; j < m.Col; j++ {
+			r = append(r, m.Slice(0, m.Row, j, j+1))
+		}
+	case 1:
+		for i := 0; i < m.Row; i++ {
+			r = append(r, m.Slice(i, i+1, 0, m.Col))
+		}
+	default:
+		panic("math: invalid axis")
+	}
+	return r
+}
+
+func (m Mat[T]) reduceAxis(axis int, f func(v Mat[T]) T) Mat[T] {
+	lines := m.lines(axis)
+	r := Mat[T]{Row: 1, Col: len(lines), Data: make([]T, len(lines))}
+	if axis == 1 {
+		r.Row, r.Col = r.Col, r.Row
+	}
+	for k, v := range lines {
+		r.Data[k] = f(v)
+	}
+	return r
+}
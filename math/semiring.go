@@ -0,0 +1,195 @@
+// Copyright 2023 Changkun Ou <changkun.de>. All rights reserved.
+// Use of this source code is governed by a MIT license that
+// can be found in the LICENSE file.
+
+package math
+
+import (
+	"fmt"
+	"math"
+)
+
+// SemiringOp identifies a built-in semiring, which allows a backend such
+// as the GPU to run a semiring multiplication without calling its Add
+// and Mul functions.
+type SemiringOp int
+
+// All built-in semirings.
+const (
+	CustomSemiring SemiringOp = iota
+	PlusTimesSemiring
+	MinPlusSemiring
+	MaxPlusSemiring
+	OrAndSemiring
+)
+
+// Semiring defines the addition and the multiplication that replace
+// + and * of the matrix multiplication, where Zero is the identity of
+// Add, e.g. the distance +∞ of the min-plus semiring.
+type Semiring[T Type] struct {
+	Add  func(a, b T) T
+	Mul  func(a, b T) T
+	Zero T
+	Op   SemiringOp
+}
+
+// PlusTimes returns the ordinary (+, *) semiring.
+func PlusTimes[T Type]() Semiring[T] {
+	return Semiring[T]{
+		Add: func(a, b T) T { return a + b },
+		Mul: func(a, b T) T { return a * b },
+		Op:  PlusTimesSemiring,
+	}
+}
+
+// MinPlus returns the (min, +) semiring, whose multiplication computes
+// the lengths of the shortest paths of two steps. Its zero is +∞ for
+// floating-point types and the maximum value for integer types, which
+// absorbs the addition.
+func MinPlus[T Type]() Semiring[T] {
+	less := lessFunc[T]()
+	zero, _ := bounds[T]()
+	return Semiring[T]{
+		Add: func(a, b T) T {
+			if less(b, a) {
+				return b
+			}
+			return a
+		},
+		Mul:  absorb(zero),
+		Zero: zero,
+		Op:   MinPlusSemiring,
+	}
+}
+
+// MaxPlus returns the (max, +) semiring, whose multiplication computes
+// the lengths of the longest paths of two steps. Its zero is -∞ for
+// floating-point types and the minimum value for signed integer types.
+// It panics for unsigned types, whose minimum 0 is a valid weight and
+// hence cannot absorb the addition.
+func MaxPlus[T Type]() Semiring[T] {
+	var v T
+	switch any(v).(type) {
+	case uint8, uint32:
+		panic(fmt.Sprintf("math: max-plus semiring of unsigned type %T", v))
+	}
+
+	less := lessFunc[T]()
+	_, zero := bounds[T]()
+	return Semiring[T]{
+		Add: func(a, b T) T {
+			if less(a, b) {
+				return b
+			}
+			return a
+		},
+		Mul:  absorb(zero),
+		Zero: zero,
+		Op:   MaxPlusSemiring,
+	}
+}
+
+// OrAnd returns the boolean (or, and) semiring, where a non-zero element
+// is true. Its multiplication computes the reachability of two steps and
+// results in 0 or 1.
+func OrAnd[T Type]() Semiring[T] {
+	var zero T
+	one := fromFloat64[T](1)
+	return Semiring[T]{
+		Add: func(a, b T) T {
+			if a != zero || b != zero {
+				return one
+			}
+			return zero
+		},
+		Mul: func(a, b T) T {
+			if a != zero && b != zero {
+				return one
+			}
+			return zero
+		},
+		Op: OrAndSemiring,
+	}
+}
+
+// MulSemiring applies matrix multiplication of two given matrices over
+// the semiring sr, and returns the resulting matrix. Every element of
+// the result starts from sr.Zero and accumulates sr.Mul(m[i][k], n[k][j])
+// in ascending k by sr.Add.
+//
+// This is a blocking version of matrix multiplication in jki order as
+// Mat[T].Mul.
+func MulSemiring[T Type](m, n Mat[T], sr Semiring[T]) Mat[T] {
+	if m.Col != n.Row {
+		panic("math: mismatched matrix dimension")
+	}
+
+	const blockSize = 4
+
+	r := Mat[T]{
+		Row:  m.Row,
+		Col:  n.Col,
+		Data: make([]T, m.Row*n.Col),
+	}
+	for i := range r.Data {
+		r.Data[i] = sr.Zero
+	}
+
+	for kk := 0; kk < m.Col; kk += blockSize {
+		ke := kk + blockSize
+		if ke > m.Col {
+			ke = m.Col
+		}
+		for jj := 0; jj < n.Col; jj += blockSize {
+			je := jj + blockSize
+			if je > n.Col {
+				je = n.Col
+			}
+			for k := kk; k < ke; k++ {
+				for j := jj; j < je; j++ {
+					rr := n.Get(k, j)
+					for i := 0; i < m.Row; i++ {
+						r.Set(i, j, sr.Add(r.Get(i, j), sr.Mul(m.Get(i, k), rr)))
+					}
+				}
+			}
+		}
+	}
+	return r
+}
+
+// absorb returns the multiplication of the min-plus and the max-plus
+// semirings, which adds the operands unless one of them is the zero.
+// The zero absorbs the other operand, which prevents an integer zero
+// from overflowing.
+func absorb[T Type](zero T) func(a, b T) T {
+	return func(a, b T) T {
+		if a == zero || b == zero {
+			return zero
+		}
+		return a + b
+	}
+}
+
+// bounds returns the largest and the smallest values of type T, which
+// are the infinities for floating-point types.
+func bounds[T Type]() (max, min T) {
+	var v T
+	switch any(v).(type) {
+	case int8:
+		return as[T](int8(math.MaxInt8)), as[T](int8(math.MinInt8))
+	case uint8:
+		return as[T](uint8(math.MaxUint8)), as[T](uint8(0))
+	case uint32:
+		return as[T](uint32(math.MaxUint32)), as[T](uint32(0))
+	case int32:
+		return as[T](int32(math.MaxInt32)), as[T](int32(math.MinInt32))
+	case int64:
+		return as[T](int64(math.MaxInt64)), as[T](int64(math.MinInt64))
+	case float32:
+		return as[T](float32(math.Inf(1))), as[T](float32(math.Inf(-1)))
+	case float64:
+		return as[T](math.Inf(1)), as[T](math.Inf(-1))
+	}
+	panic("unknown bounds for type")
+}
@@ -0,0 +1,292 @@
+// Copyright 2023 Changkun Ou <changkun.de>. All rights reserved.
+// Use of this source code is governed by a MIT license that
+// can be found in the LICENSE file.
+
+package math
+
+import "sort"
+
+// CSR represents a sparse matrix in compressed sparse row format.
+//
+// The column indices and the values of the non-zero elements of the
+// i-th row are ColIdx[RowPtr[i]:RowPtr[i+1]] and Data[RowPtr[i]:
+// RowPtr[i+1]], where the column indices of every row are ascending.
+type CSR[T Type] struct {
+	Row    int
+	Col    int
+	RowPtr []int
+	ColIdx []int
+	Data   []T
+}
+
+// CSC represents a sparse matrix in compressed sparse column format,
+// which is the transpose of CSR: the row indices and the values of the
+// non-zero elements of the j-th column are RowIdx[ColPtr[j]:ColPtr[j+1]]
+// and Data[ColPtr[j]:ColPtr[j+1]].
+type CSC[T Type] struct {
+	Row    int
+	Col    int
+	ColPtr []int
+	RowIdx []int
+	Data   []T
+}
+
+// NewCSR returns a row x col sparse matrix from triplets, whose k-th
+// element is vals[k] at (rows[k], cols[k]). The values of duplicated
+// positions are summed.
+func NewCSR[T Type](row, col int, rows, cols []int, vals []T) CSR[T] {
+	ptr, idx, data := compress(row, col, rows, cols, vals)
+	return CSR[T]{Row: row, Col: col, RowPtr: ptr, ColIdx: idx, Data: data}
+}
+
+// NewCSC returns a row x col sparse matrix from triplets as NewCSR.
+func NewCSC[T Type](row, col int, rows, cols []int, vals []T) CSC[T] {
+	ptr, idx, data := compress(col, row, cols, rows, vals)
+	return CSC[T]{Row: row, Col: col, ColPtr: ptr, RowIdx: idx, Data: data}
+}
+
+// CSRFromMat returns the non-zero elements of m as a sparse matrix.
+func CSRFromMat[T Type](m Mat[T]) CSR[T] {
+	var zero T
+	r := CSR[T]{Row: m.Row, Col: m.Col, RowPtr: make([]int, m.Row+1)}
+	for i := 0; i < m.Row; i++ {
+		for j := 0; j < m.Col; j++ {
+			if v := m.Get(i, j); v != zero {
+				r.ColIdx = append(r.ColIdx, j)
+				r.Data = append(r.Data, v)
+			}
+		}
+		r.RowPtr[i+1] = len(r.Data)
+	}
+	return r
+}
+
+// CSCFromMat returns the non-zero elements of m as a sparse matrix.
+func CSCFromMat[T Type](m Mat[T]) CSC[T] {
+	return CSRFromMat(m.View().T().Clone()).transpose()
+}
+
+// NNZ returns the number of stored elements.
+func (s CSR[T]) NNZ() int { return len(s.Data) }
+
+// NNZ returns the number of stored elements.
+func (s CSC[T]) NNZ() int { return len(s.Data) }
+
+// Get returns the element at (i, j).
+func (s CSR[T]) Get(i, j int) T {
+	if i < 0 || i >= s.Row || j < 0 || j >= s.Col {
+		panic("math: index out of range")
+	}
+	return find(s.ColIdx[s.RowPtr[i]:s.RowPtr[i+1]], s.Data[s.RowPtr[i]:], j)
+}
+
+// Get returns the element at (i, j).
+func (s CSC[T]) Get(i, j int) T {
+	if i < 0 || i >= s.Row || j < 0 || j >= s.Col {
+		panic("math: index out of range")
+	}
+	return find(s.RowIdx[s.ColPtr[j]:s.ColPtr[j+1]], s.Data[s.ColPtr[j]:], i)
+}
+
+// Mat returns the sparse matrix as a dense matrix.
+func (s CSR[T]) Mat() Mat[T] {
+	r := Mat[T]{Row: s.Row, Col: s.Col, Data: make([]T, s.Row*s.Col)}
+	for i := 0; i < s.Row; i++ {
+		for k := s.RowPtr[i]; k < s.RowPtr[i+1]; k++ {
+			r.Data[i*s.Col+s.ColIdx[k]] = s.Data[k]
+		}
+	}
+	return r
+}
+
+// Mat returns the sparse matrix as a dense matrix.
+func (s CSC[T]) Mat() Mat[T] {
+	return s.transpose().Mat().View().T().Clone()
+}
+
+// CSC converts the matrix to compressed sparse column format.
+func (s CSR[T]) CSC() CSC[T] {
+	return s.transposeCSR().transpose()
+}
+
+// CSR converts the matrix to compressed sparse row format.
+func (s CSC[T]) CSR() CSR[T] {
+	return s.transpose().transposeCSR()
+}
+
+// MulVec returns the product of the sparse matrix and a dense vector:
+// r = s*x
+//
+// The rows of the result are computed in parallel.
+func (s CSR[T]) MulVec(x []T) []T {
+	if len(x) != s.Col {
+		panic("math: mismatched matrix dimension")
+	}
+
+	r := make([]T, s.Row)
+	parallel(s.Row, func(i int) {
+		var sum T
+		for k := s.RowPtr[i]; k < s.RowPtr[i+1]; k++ {
+			sum += s.Data[k] * x[s.ColIdx[k]]
+		}
+		r[i] = sum
+	})
+	return r
+}
+
+// Mul returns the product of the sparse matrix and a dense matrix:
+// r = s*m
+//
+// The rows of the result are computed in parallel.
+func (s CSR[T]) Mul(m Mat[T]) Mat[T] {
+	if s.Col != m.Row {
+		panic("math: mismatched matrix dimension")
+	}
+
+	r := Mat[T]{Row: s.Row, Col: m.Col, Data: make([]T, s.Row*m.Col)}
+	parallel(s.Row, func(i int) {
+		out := r.Data[i*m.Col : (i+1)*m.Col]
+		for k := s.RowPtr[i]; k < s.RowPtr[i+1]; k++ {
+			v, row := s.Data[k], s.ColIdx[k]
+			for j := range out {
+				out[j] += v * m.Get(row, j)
+			}
+		}
+	})
+	return r
+}
+
+// MulVec returns the product of the sparse matrix and a dense vector:
+// r = s*x
+//
+// The columns of a CSC matrix scatter into all rows of the result,
+// hence the product is computed sequentially. Convert the matrix to
+// CSR for repeated parallel products.
+func (s CSC[T]) MulVec(x []T) []T {
+	if len(x) != s.Col {
+		panic("math: mismatched matrix dimension")
+	}
+
+	r := make([]T, s.Row)
+	for j := 0; j < s.Col; j++ {
+		for k := s.ColPtr[j]; k < s.ColPtr[j+1]; k++ {
+			r[s.RowIdx[k]] += s.Data[k] * x[j]
+		}
+	}
+	return r
+}
+
+// Mul returns the product of the sparse matrix and a dense matrix:
+// r = s*m
+//
+// The columns of the result are computed in parallel.
+func (s CSC[T]) Mul(m Mat[T]) Mat[T] {
+	if s.Col != m.Row {
+		panic("math: mismatched matrix dimension")
+	}
+
+	r := Mat[T]{Row: s.Row, Col: m.Col, Data: make([]T, s.Row*m.Col)}
+	parallel(m.Col, func(j int) {
+		for c := 0; c < s.Col; c++ {
+			v := m.Get(c, j)
+			for k := s.ColPtr[c]; k < s.ColPtr[c+1]; k++ {
+				r.Data[s.RowIdx[k]*m.Col+j] += s.Data[k] * v
+			}
+		}
+	})
+	return r
+}
+
+// transpose reinterprets a CSC matrix as the CSR matrix of its
+// transpose, which shares the elements.
+func (s CSC[T]) transpose() CSR[T] {
+	return CSR[T]{Row: s.Col, Col: s.Row, RowPtr: s.ColPtr, ColIdx: s.RowIdx, Data: s.Data}
+}
+
+// transpose reinterprets a CSR matrix as the CSC matrix of its
+// transpose, which shares the elements.
+func (s CSR[T]) transpose() CSC[T] {
+	return CSC[T]{Row: s.Col, Col: s.Row, ColPtr: s.RowPtr, RowIdx: s.ColIdx, Data: s.Data}
+}
+
+// transposeCSR returns the transpose of a CSR matrix in CSR format,
+// whose column indices are ascending since the rows are visited in order.
+func (s CSR[T]) transposeCSR() CSR[T] {
+	r := CSR[T]{
+		Row:    s.Col,
+		Col:    s.Row,
+		RowPtr: make([]int, s.Col+1),
+		ColIdx: make([]int, len(s.Data)),
+		Data:   make([]T, len(s.Data)),
+	}
+	for _, j := range s.ColIdx {
+		r.RowPtr[j+1]++
+	}
+	for j := 0; j < s.Col; j++ {
+		r.RowPtr[j+1] += r.RowPtr[j]
+	}
+	next := append([]int(nil), r.RowPtr[:s.Col]...)
+	for i := 0; i < s.Row; i++ {
+		for k := s.RowPtr[i]; k < s.RowPtr[i+1]; k++ {
+			j := s.ColIdx[k]
+			r.ColIdx[next[j]] = i
+			r.Data[next[j]] = s.Data[k]
+			next[j]++
+		}
+	}
+	return r
+}
+
+// compress sorts the triplets by their major and then minor index, sums
+// the duplicates and returns the compressed pointers, minor indices and
+// values.
+func compress[T Type](major, minor int, majors, minors []int, vals []T) ([]int, []int, []T) {
+	if len(majors) != len(vals) || len(minors) != len(vals) {
+		panic("math: mismatched triplet length")
+	}
+
+	order := make([]int, len(vals))
+	for k := range order {
+		if majors[k] < 0 || majors[k] >= major || minors[k] < 0 || minors[k] >= minor {
+			panic("math: index out of range")
+		}
+		order[k] = k
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		ka, kb := order[a], order[b]
+		if majors[ka] != majors[kb] {
+			return majors[ka] < majors[kb]
+		}
+		return minors[ka] < minors[kb]
+	})
+
+	ptr := make([]int, major+1)
+	idx := make([]int, 0, len(vals))
+	data := make([]T, 0, len(vals))
+	last := -1
+	for _, k := range order {
+		if n := len(idx); n > 0 && last == majors[k] && idx[n-1] == minors[k] {
+			data[n-1] += vals[k]
+			continue
+		}
+		last = majors[k]
+		idx = append(idx, minors[k])
+		data = append(data, vals[k])
+		ptr[majors[k]+1]++
+	}
+	for i := 0; i < major; i++ {
+		ptr[i+1] += ptr[i]
+	}
+	return ptr, idx, data
+}
+
+// find returns the value whose index is i in the ascending indices idx,
+// or zero if there is none.
+func find[T Type](idx []int, data []T, i int) T {
+	k := sort.SearchInts(idx, i)
+	if k < len(idx) && idx[k] == i {
+		return data[k]
+	}
+	var zero T
+	return zero
+}
@@ -0,0 +1,119 @@
+// Copyright 2023 Changkun Ou <changkun.de>. All rights reserved.
+// Use of this source code is governed by a MIT license that
+// can be found in the LICENSE file.
+
+package math
+
+// SVD is the thin singular value decomposition of a MxN matrix A, such
+// that A = U*diag(σ)*V' where U is a MxK and V is a NxK matrix with
+// orthonormal columns, σ are the K = min(M, N) singular values in
+// descending order.
+type SVD[T Float] struct {
+	u, v Mat[T]
+	s    []T
+}
+
+// FactorSVD computes the singular value decomposition of a matrix a
+// using the one-sided Jacobi method.
+//
+// If mul is not nil, the rotated matrix A*V is recomputed by mul at the
+// beginning of every sweep, which discards the rounding errors that the
+// rotations accumulate, and allows the multiplications to run on a GPU
+// by passing gpu.Mul.
+func FactorSVD[T Float](a Mat[T], mul MulFunc[T]) (*SVD[T], error) {
+	if a.Row < a.Col {
+		f, err := FactorSVD(transpose(a), mul)
+		if err != nil {
+			return nil, err
+		}
+		f.u, f.v = f.v, f.u
+		return f, nil
+	}
+
+	n := a.Col
+	u := a.Clone()
+	v := Mat[T]{Row: n, Col: n, Data: make([]T, n*n)}
+	for i := 0; i < n; i++ {
+		v.Set(i, i, 1)
+	}
+
+	// Columns whose inner product is below the absolute tolerance are
+	// considered orthogonal, which bounds the number of sweeps if the
+	// columns are recomputed by mul with limited precision.
+	eps := epsilon[T]()
+	norm := frobenius(a)
+	tol := T(n) * eps * norm * norm
+	converged := false
+	for sweep := 0; sweep < maxSweeps && !converged; sweep++ {
+		if mul != nil && sweep > 0 {
+			u = mul(a, v)
+		}
+
+		converged = true
+		for p := 0; p < n-1; p++ {
+			for q := p + 1; q < n; q++ {
+				var alpha, beta, gamma T
+				for i := 0; i < u.Row; i++ {
+					up, uq := u.Get(i, p), u.Get(i, q)
+					alpha += up * up
+					beta += uq * uq
+					gamma += up * uq
+				}
+				if abs(gamma) <= eps*sqrt(alpha*beta) || abs(gamma) <= tol {
+					continue
+				}
+				converged = false
+
+				c, s := rotation(alpha, beta, gamma)
+				rotate(u, p, q, c, s)
+				rotate(v, p, q, c, s)
+			}
+		}
+	}
+	if !converged {
+		return nil, ErrNoConvergence
+	}
+
+	// The singular values are the norms of the columns of A*V.
+	s := make([]T, n)
+	for j := 0; j < n; j++ {
+		col := u.Slice(0, u.Row, j, j+1)
+		s[j] = frobenius(col)
+		if s[j] != 0 {
+			for i := 0; i < u.Row; i++ {
+				col.Set(i, 0, col.Get(i, 0)/s[j])
+			}
+		}
+	}
+	s = sortColumns(s, &u, &v)
+	return &SVD[T]{u: u, v: v, s: s}, nil
+}
+
+// U returns the left singular vectors as the columns of a matrix.
+func (f *SVD[T]) U() Mat[T] { return f.u.Clone() }
+
+// V returns the right singular vectors as the columns of a matrix.
+func (f *SVD[T]) V() Mat[T] { return f.v.Clone() }
+
+// Values returns the singular values in descending order.
+func (f *SVD[T]) Values() []T { return append([]T(nil), f.s...) }
+
+// Rank returns the number of singular values that are non-zero to
+// working precision.
+func (f *SVD[T]) Rank() int {
+	if len(f.s) == 0 {
+		return 0
+	}
+	m := f.u.Row
+	if f.v.Row > m {
+		m = f.v.Row
+	}
+	tol := T(m) * epsilon[T]() * f.s[0]
+	r := 0
+	for _, v := range f.s {
+		if v > tol {
+			r++
+		}
+	}
+	return r
+}
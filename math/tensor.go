@@ -0,0 +1,333 @@
+// Copyright 2023 Changkun Ou <changkun.de>. All rights reserved.
+// Use of this source code is governed by a MIT license that
+// can be found in the LICENSE file.
+
+package math
+
+// Tensor represents an N-dimensional array.
+//
+// The element at index (i0, i1, ..., in) is stored at Data[i0*Strides[0]+
+// i1*Strides[1]+...+in*Strides[n]]. A dense tensor stores its elements in
+// row-major order, whereas the views returned by Permute, Slice and
+// BroadcastTo share the elements with the original tensor and only
+// differ in their strides. A dimension with a zero stride repeats its
+// first element.
+type Tensor[T Type] struct {
+	Shape   []int
+	Strides []int
+	Data    []T
+}
+
+// NewTensor returns a dense tensor of the given shape whose elements
+// are all zero.
+func NewTensor[T Type](shape ...int) Tensor[T] {
+	return Tensor[T]{
+		Shape:   append([]int(nil), shape...),
+		Strides: denseStrides(shape),
+		Data:    make([]T, size(shape)),
+	}
+}
+
+// TensorFromMat returns a 2-D tensor that shares its elements with m.
+func TensorFromMat[T Type](m Mat[T]) Tensor[T] {
+	return Tensor[T]{
+		Shape:   []int{m.Row, m.Col},
+		Strides: []int{m.RowStride(), 1},
+		Data:    m.Data,
+	}
+}
+
+// Mat returns a 2-D tensor as a matrix. The returned matrix shares its
+// elements with the tensor if the elements of each row are adjacent in
+// memory, otherwise the elements are copied into a dense matrix.
+func (t Tensor[T]) Mat() Mat[T] {
+	if len(t.Shape) != 2 {
+		panic("math: tensor is not 2-D")
+	}
+	row, col := t.Shape[0], t.Shape[1]
+	if row == 0 || col == 0 {
+		return Mat[T]{Row: row, Col: col}
+	}
+	if (t.Strides[1] != 1 && col > 1) || (t.Strides[0] < col && row > 1) {
+		return t.Clone().Mat()
+	}
+	if row == 1 {
+		return Mat[T]{Row: 1, Col: col, Data: t.Data[:col]}
+	}
+	m := Mat[T]{Row: row, Col: col, Data: t.Data[:(row-1)*t.Strides[0]+col]}
+	if t.Strides[0] != col {
+		m.Stride = t.Strides[0]
+	}
+	return m
+}
+
+// Dim returns the number of dimensions.
+func (t Tensor[T]) Dim() int { return len(t.Shape) }
+
+// Size returns the number of elements.
+func (t Tensor[T]) Size() int { return size(t.Shape) }
+
+// Index returns the element index of Data at the given position.
+func (t Tensor[T]) Index(idx ...int) int {
+	if len(idx) != len(t.Shape) {
+		panic("math: mismatched tensor dimension")
+	}
+	k := 0
+	for i, v := range idx {
+		if v < 0 || v >= t.Shape[i] {
+			panic("math: tensor index out of range")
+		}
+		k += v * t.Strides[i]
+	}
+	return k
+}
+
+// Get gets the corresponding element at the given position.
+func (t Tensor[T]) Get(idx ...int) T {
+	return t.Data[t.Index(idx...)]
+}
+
+// Set sets the given value to the tensor at the given position.
+func (t Tensor[T]) Set(v T, idx ...int) {
+	t.Data[t.Index(idx...)] = v
+}
+
+// Contiguous returns true if the tensor is dense and stores its
+// elements in row-major order.
+func (t Tensor[T]) Contiguous() bool {
+	s := 1
+	for i := len(t.Shape) - 1; i >= 0; i-- {
+		if t.Shape[i] != 1 && t.Strides[i] != s {
+			return false
+		}
+		s *= t.Shape[i]
+	}
+	return true
+}
+
+// Clone returns a dense copy of the tensor.
+func (t Tensor[T]) Clone() Tensor[T] {
+	r := NewTensor[T](t.Shape...)
+	k := 0
+	each(t.Shape, func(idx []int) {
+		r.Data[k] = t.Get(idx...)
+		k++
+	})
+	return r
+}
+
+// Reshape returns a tensor of the given shape with the same elements in
+// row-major order. One of the dimensions can be -1, which is inferred
+// from the size of the tensor. The returned tensor shares its elements
+// with t if t is contiguous, otherwise the elements are copied.
+func (t Tensor[T]) Reshape(shape ...int) Tensor[T] {
+	shape = append([]int(nil), shape...)
+	n, infer := 1, -1
+	for i, v := range shape {
+		switch {
+		case v == -1 && infer < 0:
+			infer = i
+		case v < 0:
+			panic("math: invalid tensor shape")
+		default:
+			n *= v
+		}
+	}
+	if infer >= 0 && n > 0 {
+		shape[infer] = t.Size() / n
+		n *= shape[infer]
+	}
+	if n != t.Size() {
+		panic("math: mismatched tensor size")
+	}
+
+	if !t.Contiguous() {
+		t = t.Clone()
+	}
+	return Tensor[T]{Shape: shape, Strides: denseStrides(shape), Data: t.Data}
+}
+
+// Permute returns a view of the tensor whose dimensions are reordered
+// such that the i-th dimension of the view is the axes[i]-th dimension
+// of t. For instance, Permute(1, 0) transposes a 2-D tensor.
+func (t Tensor[T]) Permute(axes ...int) Tensor[T] {
+	if len(axes) != len(t.Shape) {
+		panic("math: mismatched tensor dimension")
+	}
+	r := Tensor[T]{
+		Shape:   make([]int, len(axes)),
+		Strides: make([]int, len(axes)),
+		Data:    t.Data,
+	}
+	seen := make([]bool, len(axes))
+	for i, a := range axes {
+		if a < 0 || a >= len(axes) || seen[a] {
+			panic("math: invalid tensor axes")
+		}
+		seen[a] = true
+		r.Shape[i], r.Strides[i] = t.Shape[a], t.Strides[a]
+	}
+	return r
+}
+
+// Slice returns a view of the tensor that is restricted to the
+// indices [start, end) of the given axis.
+func (t Tensor[T]) Slice(axis, start, end int) Tensor[T] {
+	if axis < 0 || axis >= len(t.Shape) {
+		panic("math: invalid tensor axes")
+	}
+	if start < 0 || end < start || end > t.Shape[axis] {
+		panic("math: slice bounds out of range")
+	}
+	r := Tensor[T]{
+		Shape:   append([]int(nil), t.Shape...),
+		Strides: append([]int(nil), t.Strides...),
+	}
+	r.Shape[axis] = end - start
+	if r.Size() > 0 {
+		r.Data = t.Data[start*t.Strides[axis]:]
+	}
+	return r
+}
+
+// BroadcastTo returns a view of the tensor with the given shape following
+// the NumPy broadcasting rules: the shapes are aligned at their trailing
+// dimensions, and a dimension of size 1 or a missing leading dimension
+// is repeated to match the given shape.
+func (t Tensor[T]) BroadcastTo(shape ...int) Tensor[T] {
+	if len(shape) < len(t.Shape) {
+		panic("math: shapes cannot be broadcast")
+	}
+	r := Tensor[T]{
+		Shape:   append([]int(nil), shape...),
+		Strides: make([]int, len(shape)),
+		Data:    t.Data,
+	}
+	off := len(shape) - len(t.Shape)
+	for i, v := range t.Shape {
+		switch {
+		case v == shape[off+i]:
+			r.Strides[off+i] = t.Strides[i]
+		case v == 1:
+			r.Strides[off+i] = 0
+		default:
+			panic("math: shapes cannot be broadcast")
+		}
+	}
+	return r
+}
+
+// BroadcastShapes returns the shape of the result of an element-wise
+// operation between tensors of the given shapes following the NumPy
+// broadcasting rules. It panics if the shapes are not compatible.
+func BroadcastShapes(shapes ...[]int) []int {
+	n := 0
+	for _, s := range shapes {
+		if len(s) > n {
+			n = len(s)
+		}
+	}
+	r := make([]int, n)
+	for i := range r {
+		r[i] = 1
+	}
+	for _, s := range shapes {
+		off := n - len(s)
+		for i, v := range s {
+			switch w := r[off+i]; {
+			case w == v || v == 1:
+			case w == 1:
+				r[off+i] = v
+			default:
+				panic("math: shapes cannot be broadcast")
+			}
+		}
+	}
+	return r
+}
+
+// Add returns the element-wise sum of two tensors that are broadcast
+// against each other.
+func (t Tensor[T]) Add(u Tensor[T]) Tensor[T] {
+	return t.zip(u, func(a, b T) T { return a + b })
+}
+
+// Sub returns the element-wise difference of two tensors that are
+// broadcast against each other.
+func (t Tensor[T]) Sub(u Tensor[T]) Tensor[T] {
+	return t.zip(u, func(a, b T) T { return a - b })
+}
+
+// Hadamard returns the element-wise product of two tensors that are
+// broadcast against each other.
+func (t Tensor[T]) Hadamard(u Tensor[T]) Tensor[T] {
+	return t.zip(u, func(a, b T) T { return a * b })
+}
+
+// Scale returns the tensor multiplied by a scalar.
+func (t Tensor[T]) Scale(s T) Tensor[T] {
+	return t.Apply(func(v T) T { return v * s })
+}
+
+// Apply returns a new dense tensor that applies f to every element of t.
+func (t Tensor[T]) Apply(f func(T) T) Tensor[T] {
+	r := NewTensor[T](t.Shape...)
+	k := 0
+	each(t.Shape, func(idx []int) {
+		r.Data[k] = f(t.Get(idx...))
+		k++
+	})
+	return r
+}
+
+func (t Tensor[T]) zip(u Tensor[T], f func(a, b T) T) Tensor[T] {
+	shape := BroadcastShapes(t.Shape, u.Shape)
+	t, u = t.BroadcastTo(shape...), u.BroadcastTo(shape...)
+	r := NewTensor[T](shape...)
+	k := 0
+	each(shape, func(idx []int) {
+		r.Data[k] = f(t.Get(idx...), u.Get(idx...))
+		k++
+	})
+	return r
+}
+
+// each calls f with every index of the given shape in row-major order.
+func each(shape []int, f func(idx []int)) {
+	if size(shape) == 0 {
+		return
+	}
+	idx := make([]int, len(shape))
+	for {
+		f(idx)
+		i := len(idx) - 1
+		for ; i >= 0; i-- {
+			idx[i]++
+			if idx[i] < shape[i] {
+				break
+			}
+			idx[i] = 0
+		}
+		if i < 0 {
+			return
+		}
+	}
+}
+
+func size(shape []int) int {
+	n := 1
+	for _, v := range shape {
+		n *= v
+	}
+	return n
+}
+
+func denseStrides(shape []int) []int {
+	strides := make([]int, len(shape))
+	s := 1
+	for i := len(shape) - 1; i >= 0; i-- {
+		strides[i] = s
+		s *= shape[i]
+	}
+	return strides
+}
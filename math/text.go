@@ -0,0 +1,105 @@
+// Copyright 2023 Changkun Ou <changkun.de>. All rights reserved.
+// Use of this source code is governed by a MIT license that
+// can be found in the LICENSE file.
+
+package math
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// ParseError is returned by the readers of text formats, such as Matrix
+// Market and CSV, if the input is malformed.
+type ParseError struct {
+	Format string // name of the format, e.g. "mtx" or "csv"
+	Line   int    // line number, starting from 1
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("math: %s line %d: %v", e.Format, e.Line, e.Err)
+}
+
+func (e *ParseError) Unwrap() error { return e.Err }
+
+// parseFunc returns the function that parses a value of type T. Integer
+// types only accept integers, and complex types accept the format of
+// strconv.ParseComplex.
+func parseFunc[T Elem]() func(s string) (T, error) {
+	var v T
+	conv := complexFunc[T]()
+	switch any(v).(type) {
+	case int8:
+		return func(s string) (T, error) {
+			i, err := strconv.ParseInt(s, 10, 8)
+			return as[T](int8(i)), err
+		}
+	case int32:
+		return func(s string) (T, error) {
+			i, err := strconv.ParseInt(s, 10, 32)
+			return as[T](int32(i)), err
+		}
+	case int64:
+		return func(s string) (T, error) {
+			i, err := strconv.ParseInt(s, 10, 64)
+			return as[T](i), err
+		}
+	case uint8:
+		return func(s string) (T, error) {
+			u, err := strconv.ParseUint(s, 10, 8)
+			return as[T](uint8(u)), err
+		}
+	case uint32:
+		return func(s string) (T, error) {
+			u, err := strconv.ParseUint(s, 10, 32)
+			return as[T](uint32(u)), err
+		}
+	case complex64, complex128:
+		bits := 8 * TypeSize[T]()
+		return func(s string) (T, error) {
+			c, err := strconv.ParseComplex(s, bits)
+			return conv(real(c), imag(c)), err
+		}
+	case float32, Float16, BFloat16:
+		return func(s string) (T, error) {
+			f, err := strconv.ParseFloat(s, 32)
+			return conv(f, 0), err
+		}
+	}
+	return func(s string) (T, error) {
+		f, err := strconv.ParseFloat(s, 64)
+		return conv(f, 0), err
+	}
+}
+
+// formatFunc returns the function that formats a value of type T, which
+// is parsed by parseFunc without loss.
+func formatFunc[T Elem]() func(v T) string {
+	var v T
+	switch any(v).(type) {
+	case int8:
+		return func(v T) string { return strconv.FormatInt(int64(as[int8](v)), 10) }
+	case int32:
+		return func(v T) string { return strconv.FormatInt(int64(as[int32](v)), 10) }
+	case int64:
+		return func(v T) string { return strconv.FormatInt(as[int64](v), 10) }
+	case uint8:
+		return func(v T) string { return strconv.FormatUint(uint64(as[uint8](v)), 10) }
+	case uint32:
+		return func(v T) string { return strconv.FormatUint(uint64(as[uint32](v)), 10) }
+	case float32:
+		return func(v T) string { return strconv.FormatFloat(float64(as[float32](v)), 'g', -1, 32) }
+	case float64:
+		return func(v T) string { return strconv.FormatFloat(as[float64](v), 'g', -1, 64) }
+	case complex64:
+		return func(v T) string { return strconv.FormatComplex(complex128(as[complex64](v)), 'g', -1, 64) }
+	case complex128:
+		return func(v T) string { return strconv.FormatComplex(as[complex128](v), 'g', -1, 128) }
+	case Float16:
+		return func(v T) string { return strconv.FormatFloat(float64(as[Float16](v).Float32()), 'g', -1, 32) }
+	case BFloat16:
+		return func(v T) string { return strconv.FormatFloat(float64(as[BFloat16](v).Float32()), 'g', -1, 32) }
+	}
+	panic("unknown format for type")
+}
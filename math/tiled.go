@@ -0,0 +1,80 @@
+// Copyright 2023 Changkun Ou <changkun.de>. All rights reserved.
+// Use of this source code is governed by a MIT license that
+// can be found in the LICENSE file.
+
+package math
+
+// MulTiled computes dst = m*n out of core, which is intended for
+// matrices that do not fit into memory, e.g. those of OpenMapped. The
+// product is computed in tiles of at most tile x tile elements: each
+// pair of blocks of m and n is copied into memory, multiplied by mul and
+// accumulated, and each finished tile is written to dst. Hence only a
+// few tiles are in memory at once. A nil mul multiplies by Mat.Mul.
+func MulTiled[T Type](dst, m, n Mat[T], tile int, mul MulFunc[T]) {
+	if m.Col != n.Row || dst.Row != m.Row || dst.Col != n.Col {
+		panic("math: mismatched matrix dimension")
+	}
+	if tile <= 0 {
+		panic("math: non-positive tile size")
+	}
+	if mul == nil {
+		mul = Mat[T].Mul
+	}
+	if m.Col == 0 {
+		for i := 0; i < dst.Row; i++ {
+			for j := 0; j < dst.Col; j++ {
+				dst.Set(i, j, 0)
+			}
+		}
+		return
+	}
+
+	var acc Mat[T]
+	Blocks(m.Row, m.Col, n.Col, tile, tile, tile, func(i0, i1, j0, j1, k0, k1 int) bool {
+		if k0 == 0 {
+			acc = Mat[T]{Row: i1 - i0, Col: j1 - j0, Data: make([]T, (i1-i0)*(j1-j0))}
+		}
+		p := mul(m.Slice(i0, i1, k0, k1).Clone(), n.Slice(k0, k1, j0, j1).Clone())
+		for i := 0; i < p.Row; i++ {
+			for j := 0; j < p.Col; j++ {
+				acc.Data[i*acc.Col+j] += p.Get(i, j)
+			}
+		}
+		if k1 == m.Col {
+			d := dst.Slice(i0, i1, j0, j1)
+			for i := 0; i < acc.Row; i++ {
+				copy(d.Data[d.Index(i, 0):d.Index(i, acc.Col)], acc.Data[i*acc.Col:(i+1)*acc.Col])
+			}
+		}
+		return true
+	})
+}
+
+// Blocks iterates the blocks of the tiled product of an r x k and a
+// k x c matrix, whose tiles of the product are tm x tn and whose blocks
+// of the operands are tm x tk and tk x tn. For each tile [i0, i1) x
+// [j0, j1) of the product, f is called in ascending order of [k0, k1),
+// hence k0 == 0 starts and k1 == k finishes a tile. The iteration stops
+// if f returns false.
+func Blocks(r, k, c, tm, tk, tn int, f func(i0, i1, j0, j1, k0, k1 int) bool) {
+	if tm <= 0 || tk <= 0 || tn <= 0 {
+		panic("math: non-positive tile size")
+	}
+	end := func(i, tile, n int) int {
+		if i+tile < n {
+			return i + tile
+		}
+		return n
+	}
+	for i0 := 0; i0 < r; i0 += tm {
+		i1 := end(i0, tm, r)
+		for j0 := 0; j0 < c; j0 += tn {
+			j1 := end(j0, tn, c)
+			for k0 := 0; k0 < k; k0 += tk {
+				if !f(i0, i1, j0, j1, k0, end(k0, tk, k)) {
+					return
+				}
+			}
+		}
+	}
+}
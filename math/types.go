@@ -0,0 +1,167 @@
+// Copyright 2023 Changkun Ou <changkun.de>. All rights reserved.
+// Use of this source code is governed by a MIT license that
+// can be found in the LICENSE file.
+
+package math
+
+import (
+	"math"
+	"unsafe"
+)
+
+// Complex numbers are not ordered and cannot be converted from other
+// numeric types, hence generic code over Type cannot use comparisons
+// or conversions directly. The following helpers dispatch on the type
+// once and return a function that reinterprets T as its concrete type.
+
+// as reinterprets v as a value of type U, which must have the same
+// memory layout as T.
+func as[U, T any](v T) U {
+	return *(*U)(unsafe.Pointer(&v))
+}
+
+// IsComplex returns true if T is a complex type.
+func IsComplex[T Elem]() bool {
+	var v T
+	switch any(v).(type) {
+	case complex64, complex128:
+		return true
+	}
+	return false
+}
+
+// isInteger returns true if T is an integer type.
+func isInteger[T Elem]() bool {
+	var v T
+	switch any(v).(type) {
+	case int8, uint8, uint32, int32, int64:
+		return true
+	}
+	return false
+}
+
+// IsHalf returns true if T is a half-precision type.
+func IsHalf[T Elem]() bool {
+	var v T
+	switch any(v).(type) {
+	case Float16, BFloat16:
+		return true
+	}
+	return false
+}
+
+// arithmetic panics if T is a half-precision type, whose matrices only
+// store their elements, see Elem.
+func arithmetic[T Elem]() {
+	if IsHalf[T]() {
+		panic("math: arithmetic on half-precision matrices, convert them by ToFloat32")
+	}
+}
+
+// lessFunc returns the less-than comparison of type T. It panics if T
+// is a complex type.
+func lessFunc[T Elem]() func(a, b T) bool {
+	var v T
+	switch any(v).(type) {
+	case int8:
+		return func(a, b T) bool { return as[int8](a) < as[int8](b) }
+	case uint8:
+		return func(a, b T) bool { return as[uint8](a) < as[uint8](b) }
+	case uint32:
+		return func(a, b T) bool { return as[uint32](a) < as[uint32](b) }
+	case int32:
+		return func(a, b T) bool { return as[int32](a) < as[int32](b) }
+	case int64:
+		return func(a, b T) bool { return as[int64](a) < as[int64](b) }
+	case float32:
+		return func(a, b T) bool { return as[float32](a) < as[float32](b) }
+	case float64:
+		return func(a, b T) bool { return as[float64](a) < as[float64](b) }
+	case Float16:
+		return func(a, b T) bool { return as[Float16](a).Float32() < as[Float16](b).Float32() }
+	case BFloat16:
+		return func(a, b T) bool { return as[BFloat16](a).Float32() < as[BFloat16](b).Float32() }
+	case complex64, complex128:
+		panic("math: complex numbers are not ordered")
+	}
+	panic("unknown comparison for type")
+}
+
+// distFunc returns the function that computes the distance |a-b| of two
+// values of type T as a float64, i.e. the modulus of the difference for
+// complex types.
+func distFunc[T Elem]() func(a, b T) float64 {
+	var v T
+	switch any(v).(type) {
+	case int8:
+		return func(a, b T) float64 { return math.Abs(float64(as[int8](a)) - float64(as[int8](b))) }
+	case uint8:
+		return func(a, b T) float64 { return math.Abs(float64(as[uint8](a)) - float64(as[uint8](b))) }
+	case uint32:
+		return func(a, b T) float64 { return math.Abs(float64(as[uint32](a)) - float64(as[uint32](b))) }
+	case int32:
+		return func(a, b T) float64 { return math.Abs(float64(as[int32](a)) - float64(as[int32](b))) }
+	case int64:
+		return func(a, b T) float64 { return math.Abs(float64(as[int64](a) - as[int64](b))) }
+	case float32:
+		return func(a, b T) float64 { return math.Abs(float64(as[float32](a) - as[float32](b))) }
+	case float64:
+		return func(a, b T) float64 { return math.Abs(as[float64](a) - as[float64](b)) }
+	case complex64:
+		return func(a, b T) float64 {
+			c := as[complex64](a) - as[complex64](b)
+			return math.Hypot(float64(real(c)), float64(imag(c)))
+		}
+	case complex128:
+		return func(a, b T) float64 {
+			c := as[complex128](a) - as[complex128](b)
+			return math.Hypot(real(c), imag(c))
+		}
+	case Float16:
+		return func(a, b T) float64 {
+			return math.Abs(float64(as[Float16](a).Float32()) - float64(as[Float16](b).Float32()))
+		}
+	case BFloat16:
+		return func(a, b T) float64 {
+			return math.Abs(float64(as[BFloat16](a).Float32()) - float64(as[BFloat16](b).Float32()))
+		}
+	}
+	panic("unknown distance for type")
+}
+
+// complexFunc returns the function that converts the complex number
+// re+im*i to the type T. Real types discard the imaginary part, and
+// integer types truncate the real part.
+func complexFunc[T Elem]() func(re, im float64) T {
+	var v T
+	switch any(v).(type) {
+	case int8:
+		return func(re, im float64) T { return as[T](int8(re)) }
+	case uint8:
+		return func(re, im float64) T { return as[T](uint8(re)) }
+	case uint32:
+		return func(re, im float64) T { return as[T](uint32(re)) }
+	case int32:
+		return func(re, im float64) T { return as[T](int32(re)) }
+	case int64:
+		return func(re, im float64) T { return as[T](int64(re)) }
+	case float32:
+		return func(re, im float64) T { return as[T](float32(re)) }
+	case float64:
+		return func(re, im float64) T { return as[T](re) }
+	case complex64:
+		return func(re, im float64) T { return as[T](complex(float32(re), float32(im))) }
+	case complex128:
+		return func(re, im float64) T { return as[T](complex(re, im)) }
+	case Float16:
+		return func(re, im float64) T { return as[T](NewFloat16(float32(re))) }
+	case BFloat16:
+		return func(re, im float64) T { return as[T](NewBFloat16(float32(re))) }
+	}
+	panic("unknown conversion for type")
+}
+
+// fromFloat64 converts a float64 to the type T.
+func fromFloat64[T Elem](f float64) T {
+	return complexFunc[T]()(f, 0)
+}
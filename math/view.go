@@ -0,0 +1,122 @@
+// Copyright 2023 Changkun Ou <changkun.de>. All rights reserved.
+// Use of this source code is governed by a MIT license that
+// can be found in the LICENSE file.
+
+package math
+
+// Slice returns the sub-matrix of rows [r0, r1) and columns [c0, c1).
+// The returned matrix shares its elements with m.
+func (m Mat[T]) Slice(r0, r1, c0, c1 int) Mat[T] {
+	if r0 < 0 || r1 < r0 || r1 > m.Row || c0 < 0 || c1 < c0 || c1 > m.Col {
+		panic("math: slice bounds out of range")
+	}
+
+	s := m.RowStride()
+	r := Mat[T]{Row: r1 - r0, Col: c1 - c0, Stride: s}
+	if r.Row == 0 || r.Col == 0 {
+		return r
+	}
+	off := r0*s + c0
+	r.Data = m.Data[off : off+(r.Row-1)*s+r.Col]
+	return r
+}
+
+// View returns a view of m that shares its elements with m.
+func (m Mat[T]) View() View[T] {
+	return View[T]{
+		data: m.Data,
+		row:  m.Row,
+		col:  m.Col,
+		rs:   m.RowStride(),
+		cs:   1,
+	}
+}
+
+// Clone returns a dense copy of m.
+func (m Mat[T]) Clone() Mat[T] {
+	return m.View().Clone()
+}
+
+// View is a strided window onto the elements of a matrix. Unlike Mat,
+// a View can step through its columns with an arbitrary stride, which
+// allows a transposed or a column view without copying any element.
+type View[T Elem] struct {
+	data []T
+	row  int
+	col  int
+	rs   int // distance between two vertically adjacent elements
+	cs   int // distance between two horizontally adjacent elements
+}
+
+// Dims returns the number of rows and columns of the view.
+func (v View[T]) Dims() (row, col int) { return v.row, v.col }
+
+// Get gets the corresponding element at (i, j)
+func (v View[T]) Get(i, j int) T {
+	return v.data[i*v.rs+j*v.cs]
+}
+
+// Set sets the given value to the view at (i, j)
+func (v View[T]) Set(i, j int, val T) {
+	v.data[i*v.rs+j*v.cs] = val
+}
+
+// Slice returns the view of rows [r0, r1) and columns [c0, c1).
+func (v View[T]) Slice(r0, r1, c0, c1 int) View[T] {
+	if r0 < 0 || r1 < r0 || r1 > v.row || c0 < 0 || c1 < c0 || c1 > v.col {
+		panic("math: slice bounds out of range")
+	}
+
+	w := View[T]{row: r1 - r0, col: c1 - c0, rs: v.rs, cs: v.cs}
+	if w.row > 0 && w.col > 0 {
+		w.data = v.data[r0*v.rs+c0*v.cs:]
+	}
+	return w
+}
+
+// Row returns the i-th row as a 1xN view.
+func (v View[T]) Row(i int) View[T] { return v.Slice(i, i+1, 0, v.col) }
+
+// Col returns the j-th column as a Nx1 view.
+func (v View[T]) Col(j int) View[T] { return v.Slice(0, v.row, j, j+1) }
+
+// T returns the transposed view.
+func (v View[T]) T() View[T] {
+	return View[T]{data: v.data, row: v.col, col: v.row, rs: v.cs, cs: v.rs}
+}
+
+// Mat returns the view as a matrix. The returned matrix shares its
+// elements with the view if the elements of each row are adjacent in
+// memory, otherwise the elements are copied into a dense matrix.
+func (v View[T]) Mat() Mat[T] {
+	if v.row == 0 || v.col == 0 {
+		return Mat[T]{Row: v.row, Col: v.col}
+	}
+	if v.cs != 1 && v.col > 1 {
+		return v.Clone()
+	}
+	if v.row == 1 {
+		return Mat[T]{Row: 1, Col: v.col, Data: v.data[:v.col]}
+	}
+	return Mat[T]{
+		Row:    v.row,
+		Col:    v.col,
+		Stride: v.rs,
+		Data:   v.data[:(v.row-1)*v.rs+v.col],
+	}
+}
+
+// Clone returns a dense copy of the view.
+func (v View[T]) Clone() Mat[T] {
+	r := Mat[T]{
+		Row:  v.row,
+		Col:  v.col,
+		Data: make([]T, v.row*v.col),
+	}
+	for i := 0; i < v.row; i++ {
+		for j := 0; j < v.col; j++ {
+			r.Data[i*v.col+j] = v.Get(i, j)
+		}
+	}
+	return r
+}
@@ -0,0 +1,610 @@
+// Copyright 2023 Changkun Ou <changkun.de>. All rights reserved.
+// Use of this source code is governed by a MIT license that
+// can be found in the LICENSE file.
+
+package main_test
+
+import (
+	stdmath "math"
+	"math/rand"
+	"runtime"
+	"testing"
+
+	"changkun.de/x/gogpu/math"
+)
+
+func TestBroadcast(t *testing.T) {
+	m := math.Mat[float32]{
+		Row: 2, Col: 3,
+		Data: []float32{
+			1, 2, 3,
+			4, 5, 6,
+		},
+	}
+	row := math.Mat[float32]{Row: 1, Col: 3, Data: []float32{10, 20, 30}}
+	col := math.Mat[float32]{Row: 2, Col: 1, Data: []float32{1, 2}}
+
+	tests := []struct {
+		got, want math.Mat[float32]
+	}{
+		{
+			got: m.Add(row),
+			want: math.Mat[float32]{Row: 2, Col: 3, Data: []float32{
+				11, 22, 33,
+				14, 25, 36,
+			}},
+		},
+		{
+			got: m.Sub(col),
+			want: math.Mat[float32]{Row: 2, Col: 3, Data: []float32{
+				0, 1, 2,
+				2, 3, 4,
+			}},
+		},
+		{
+			got: col.Hadamard(row),
+			want: math.Mat[float32]{Row: 2, Col: 3, Data: []float32{
+				10, 20, 30,
+				20, 40, 60,
+			}},
+		},
+		{
+			got: m.Slice(0, 2, 1, 3).Scale(2),
+			want: math.Mat[float32]{Row: 2, Col: 2, Data: []float32{
+				4, 6,
+				10, 12,
+			}},
+		},
+		{
+			got:  m.SumAxis(0),
+			want: math.Mat[float32]{Row: 1, Col: 3, Data: []float32{5, 7, 9}},
+		},
+		{
+			got:  m.MeanAxis(1),
+			want: math.Mat[float32]{Row: 2, Col: 1, Data: []float32{2, 5}},
+		},
+	}
+	for i, tt := range tests {
+		if !tt.got.Eq(tt.want) {
+			t.Fatalf("#%d: got %v, want %v", i, tt.got, tt.want)
+		}
+	}
+
+	if s := m.Sum(); s != 21 {
+		t.Fatalf("Sum: got %v, want 21", s)
+	}
+	if i, j := m.ArgMax(); i != 1 || j != 2 {
+		t.Fatalf("ArgMax: got (%v, %v), want (1, 2)", i, j)
+	}
+	if idx := m.ArgMaxAxis(0); idx[0] != 1 || idx[1] != 1 || idx[2] != 1 {
+		t.Fatalf("ArgMaxAxis: got %v, want [1 1 1]", idx)
+	}
+}
+
+func TestView(t *testing.T) {
+	m := math.Mat[float32]{Row: 4, Col: 5, Data: make([]float32, 20)}
+	for i := range m.Data {
+		m.Data[i] = float32(i)
+	}
+
+	s := m.Slice(1, 3, 1, 4)
+	want := math.Mat[float32]{Row: 2, Col: 3, Data: []float32{
+		6, 7, 8,
+		11, 12, 13,
+	}}
+	if s.Dense() || s.RowStride() != 5 {
+		t.Fatalf("Slice: got stride %v, want a view of stride 5", s.RowStride())
+	}
+	if !s.Eq(want) || !want.Eq(s) {
+		t.Fatalf("Slice: got %v, want %v", s, want)
+	}
+	if c := s.Clone(); !c.Dense() || len(c.Data) != 6 || !c.Eq(want) {
+		t.Fatalf("Clone: got %v, want a dense %v", c, want)
+	}
+	if e := m.Slice(2, 2, 0, 5); e.Row != 0 || e.Col != 5 || len(e.Clone().Data) != 0 {
+		t.Fatalf("Slice: got %v, want an empty 0x5 matrix", e)
+	}
+
+	v := m.View()
+	if r, c := v.T().Dims(); r != 5 || c != 4 || v.T().Get(3, 2) != m.Get(2, 3) {
+		t.Fatalf("T: got a %vx%v view, want the transpose of %v", r, c, m)
+	}
+	tests := []struct {
+		got, want math.Mat[float32]
+	}{
+		{got: v.Row(2).Mat(), want: math.Mat[float32]{Row: 1, Col: 5, Data: []float32{10, 11, 12, 13, 14}}},
+		{got: v.Col(1).Mat(), want: math.Mat[float32]{Row: 4, Col: 1, Data: []float32{1, 6, 11, 16}}},
+		{got: v.Slice(1, 3, 1, 4).T().Mat(), want: math.Mat[float32]{Row: 3, Col: 2, Data: []float32{
+			6, 11,
+			7, 12,
+			8, 13,
+		}}},
+		{got: v.T().Row(4).Clone(), want: math.Mat[float32]{Row: 1, Col: 4, Data: []float32{4, 9, 14, 19}}},
+	}
+	for i, tt := range tests {
+		if !tt.got.Eq(tt.want) {
+			t.Fatalf("#%d: got %v, want %v", i, tt.got, tt.want)
+		}
+	}
+
+	// Views share their elements with the matrix, except the copy of a
+	// transposed view.
+	s.Set(0, 0, -1)
+	v.Col(4).Set(3, 0, -2)
+	if m.Get(1, 1) != -1 || m.Get(3, 4) != -2 {
+		t.Fatalf("Set: views do not share elements with %v", m)
+	}
+	v.T().Mat().Set(0, 0, -3)
+	if m.Get(0, 0) != 0 {
+		t.Fatalf("Mat: transposed view shares elements with %v", m)
+	}
+
+	// The operations on views equal those on their dense copies.
+	n := m.View().T().Slice(1, 4, 0, 3).Mat()
+	if got, want := s.Mul(n), s.Clone().Mul(n.Clone()); !got.Eq(want) {
+		t.Fatalf("Mul: got %v, want %v", got, want)
+	}
+	if got, want := s.MulNaive(n), s.Clone().MulNaive(n.Clone()); !got.Eq(want) {
+		t.Fatalf("MulNaive: got %v, want %v", got, want)
+	}
+	if got, want := s.Add(m.Slice(2, 4, 2, 5)), s.Clone().Add(m.Slice(2, 4, 2, 5).Clone()); !got.Eq(want) {
+		t.Fatalf("Add: got %v, want %v", got, want)
+	}
+	if s.Eq(s.Clone().Scale(2)) || !s.Eq(s.Clone()) {
+		t.Fatalf("Eq: inconsistent comparison of a view and its copy")
+	}
+}
+
+func TestTensor(t *testing.T) {
+	x := math.NewTensor[float32](2, 3, 4)
+	for i := range x.Data {
+		x.Data[i] = float32(i)
+	}
+
+	if v := x.Permute(2, 0, 1).Get(3, 1, 2); v != x.Get(1, 2, 3) {
+		t.Fatalf("Permute: got %v, want %v", v, x.Get(1, 2, 3))
+	}
+	if v := x.Slice(1, 1, 3).Get(1, 0, 2); v != x.Get(1, 1, 2) {
+		t.Fatalf("Slice: got %v, want %v", v, x.Get(1, 1, 2))
+	}
+	if v := x.Permute(1, 0, 2).Reshape(3, -1).Get(2, 5); v != x.Get(1, 2, 1) {
+		t.Fatalf("Reshape: got %v, want %v", v, x.Get(1, 2, 1))
+	}
+
+	// (2, 3, 4) + (3, 1) broadcasts to (2, 3, 4).
+	y := math.NewTensor[float32](3, 1)
+	y.Data = []float32{100, 200, 300}
+	z := x.Add(y)
+	if s := z.Shape; len(s) != 3 || s[0] != 2 || s[1] != 3 || s[2] != 4 {
+		t.Fatalf("Add: unexpected shape %v", s)
+	}
+	if v := z.Get(1, 2, 3); v != x.Get(1, 2, 3)+300 {
+		t.Fatalf("Add: got %v, want %v", v, x.Get(1, 2, 3)+300)
+	}
+
+	m := math.NewRandMat[float32](5, 7, math.WithSeed(1)).Slice(1, 4, 2, 6)
+	if !math.TensorFromMat(m).Mat().Eq(m) {
+		t.Fatalf("Mat: inconsistent round trip")
+	}
+	if !math.TensorFromMat(m).Permute(1, 0).Mat().Eq(m.View().T().Mat()) {
+		t.Fatalf("Mat: inconsistent transposed round trip")
+	}
+}
+
+func TestTypes(t *testing.T) {
+	if math.TypeSize[int64]() != 8 || math.TypeSize[float64]() != 8 ||
+		math.TypeSize[complex64]() != 8 || math.TypeSize[complex128]() != 16 {
+		t.Fatalf("TypeSize: unexpected size")
+	}
+
+	testTypeMul[int64](t)
+	testTypeMul[float64](t)
+	testTypeMul[complex64](t)
+	testTypeMul[complex128](t)
+
+	c := math.NewRandMat[complex128](4, 4, math.WithSeed(2))
+	if c.Sum() == complex(real(c.Sum()), 0) {
+		t.Fatalf("NewRandMat: complex elements have no imaginary part")
+	}
+	d := c.Clone()
+	d.Set(0, 0, d.Get(0, 0)+1i)
+	if d.Eq(c) {
+		t.Fatalf("Eq: ignores the imaginary part")
+	}
+
+	inf := math.Mat[float64]{Row: 1, Col: 2, Data: []float64{stdmath.Inf(1), stdmath.Inf(-1)}}
+	if !inf.Eq(inf.Clone()) {
+		t.Fatalf("Eq: infinities are not equal")
+	}
+
+	u := math.Mat[uint8]{Row: 1, Col: 2, Data: []uint8{1, 2}}
+	if u.Eq(math.Mat[uint8]{Row: 1, Col: 2, Data: []uint8{2, 1}}) {
+		t.Fatalf("Eq: unsigned difference wraps around")
+	}
+}
+
+func testTypeMul[T math.Type](t *testing.T) {
+	t.Helper()
+
+	m1 := math.NewRandMat[T](17, 9, math.WithSeed(3))
+	m2 := math.NewRandMat[T](9, 13, math.WithSeed(4))
+	var k T
+	for i := range m1.Data {
+		k++
+		m1.Data[i] += k
+	}
+	for i := range m2.Data {
+		k--
+		m2.Data[i] += k
+	}
+
+	want := m1.MulNaive(m2)
+	if got := m1.Mul(m2); !got.Eq(want) {
+		t.Fatalf("%T: Mul receives different results compare to MulNaive", k)
+	}
+	if !m1.Eq(m1.Clone()) || m1.Eq(m1.Scale(2)) {
+		t.Fatalf("%T: unexpected Eq", k)
+	}
+}
+
+func TestFloat16(t *testing.T) {
+	for _, tt := range []struct {
+		f float32
+		h math.Float16
+	}{
+		{0, 0x0000},
+		{1, 0x3c00},
+		{-2, 0xc000},
+		{65504, 0x7bff},
+		{65519, 0x7bff},
+		{65520, 0x7c00}, // rounds up to infinity
+		{float32(stdmath.Inf(-1)), 0xfc00},
+		{1 + 0x1p-11, 0x3c00},    // tie to even
+		{1 + 0x3p-11, 0x3c02},    // tie to even
+		{1 + 0x1.01p-11, 0x3c01}, // above the tie
+		{0x1p-14, 0x0400},        // smallest normal
+		{0x1p-24, 0x0001},        // smallest subnormal
+		{0x1p-25, 0x0000},        // tie to even
+		{0x1.8p-25, 0x0001},
+		{0x3p-25, 0x0002},     // tie to even
+		{0x1.ffcp-15, 0x0400}, // rounds up to the smallest normal
+	} {
+		if h := math.NewFloat16(tt.f); h != tt.h {
+			t.Fatalf("NewFloat16(%v): got %#04x, want %#04x", tt.f, h, tt.h)
+		}
+	}
+
+	// Every Float16 is exactly representable by a float32.
+	for i := 0; i < 1<<16; i++ {
+		h := math.Float16(i)
+		f := h.Float32()
+		if f != f {
+			if i&0x7c00 != 0x7c00 || i&0x3ff == 0 {
+				t.Fatalf("%#04x: unexpected NaN", i)
+			}
+			if g := math.NewFloat16(f); g.Float32() == g.Float32() {
+				t.Fatalf("%#04x: NaN becomes %#04x", i, g)
+			}
+			continue
+		}
+		if g := math.NewFloat16(f); g != h {
+			t.Fatalf("%#04x: round trip results in %#04x", i, g)
+		}
+	}
+}
+
+func TestBFloat16(t *testing.T) {
+	for _, tt := range []struct {
+		f float32
+		h math.BFloat16
+	}{
+		{0, 0x0000},
+		{1, 0x3f80},
+		{-2, 0xc000},
+		{1 + 0x1p-8, 0x3f80}, // tie to even
+		{1 + 0x3p-8, 0x3f82}, // tie to even
+		{stdmath.MaxFloat32, 0x7f80},
+		{float32(stdmath.NaN()), 0x7fc0},
+	} {
+		if h := math.NewBFloat16(tt.f); h != tt.h {
+			t.Fatalf("NewBFloat16(%v): got %#04x, want %#04x", tt.f, h, tt.h)
+		}
+	}
+	for i := 0; i < 1<<16; i++ {
+		h := math.BFloat16(i)
+		if f := h.Float32(); f == f && math.NewBFloat16(f) != h {
+			t.Fatalf("%#04x: round trip results in %#04x", i, math.NewBFloat16(f))
+		}
+	}
+}
+
+func TestHalfMat(t *testing.T) {
+	testHalfMat[math.Float16](t)
+	testHalfMat[math.BFloat16](t)
+}
+
+// testHalfMat checks that a half-precision matrix only stores its
+// elements and rejects arithmetic on their bits.
+func testHalfMat[T math.Half](t *testing.T) {
+	f := math.Mat[float32]{Row: 2, Col: 2, Data: []float32{1, 2, -3, 0.5}}
+	h := math.FromFloat32[T](f)
+	if !math.ToFloat32(h).Eq(f) || !h.Eq(h.View().T().Clone().View().T().Clone()) {
+		t.Fatalf("inconsistent round trip of %v", f)
+	}
+	if i, j := h.ArgMax(); i != 0 || j != 1 || math.ToFloat32(h.MinAxis(0)).Data[0] != -3 {
+		t.Fatalf("ArgMax: got (%v, %v), want (0, 1)", i, j)
+	}
+	if got := math.MulMixed(h, h); !got.Eq(f.Mul(f)) {
+		t.Fatalf("MulMixed: got %v, want %v", got, f.Mul(f))
+	}
+
+	var one T
+	for name, op := range map[string]func(){
+		"Add":      func() { h.Add(h) },
+		"Sub":      func() { h.Sub(h) },
+		"Hadamard": func() { h.Hadamard(h) },
+		"Scale":    func() { h.Scale(one) },
+		"Apply":    func() { h.Apply(func(v T) T { return v }) },
+		"Sum":      func() { h.Sum() },
+		"Mean":     func() { h.Mean() },
+		"SumAxis":  func() { h.SumAxis(0) },
+		"MeanAxis": func() { h.MeanAxis(1) },
+		"Mul":      func() { h.Mul(h) },
+		"MulNaive": func() { h.MulNaive(h) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("%s: want a panic for %T", name, one)
+				}
+			}()
+			op()
+		}()
+	}
+}
+
+func TestQuantize(t *testing.T) {
+	m := math.NewRandMat[float32](7, 9, math.WithSeed(5)).Apply(func(v float32) float32 { return 4*v - 1 })
+	testQuantize[int8](t, m)
+	testQuantize[uint8](t, m)
+
+	// The zero is represented exactly even if all elements are positive.
+	p := math.Mat[float32]{Row: 1, Col: 3, Data: []float32{0.5, 2, 0}}
+	if q := math.QuantizeFrom[uint8](p); q.Dequantize().Get(0, 2) != 0 || q.ZeroPoint != 0 {
+		t.Fatalf("QuantizeFrom: zero is not exact: %+v", q)
+	}
+
+	// Elements beyond the range are saturated.
+	q := math.Quantize[int8](math.Mat[float32]{Row: 1, Col: 2, Data: []float32{1000, -1000}}, 1, 3)
+	if q.Mat.Data[0] != 127 || q.Mat.Data[1] != -128 {
+		t.Fatalf("Quantize: unexpected saturation %v", q.Mat.Data)
+	}
+}
+
+func testQuantize[T math.Q8](t *testing.T, m math.Mat[float32]) {
+	t.Helper()
+
+	q := math.QuantizeFrom[T](m)
+	d := q.Dequantize()
+	for i := range m.Data {
+		if e := stdmath.Abs(float64(d.Data[i] - m.Data[i])); e > float64(q.Scale)/2+1e-6 {
+			t.Fatalf("%T: error %v exceeds half of the scale %v", q.Mat.Data[0], e, q.Scale)
+		}
+	}
+}
+
+func TestSparse(t *testing.T) {
+	// A random matrix where about 90% of the elements are zero.
+	m := math.NewRandMat[float32](37, 23, math.WithSeed(6)).Apply(func(v float32) float32 {
+		if v < 0.9 {
+			return 0
+		}
+		return v
+	})
+	x := math.NewRandMat[float32](23, 1, math.WithSeed(7))
+	d := math.NewRandMat[float32](23, 11, math.WithSeed(8))
+
+	csr := math.CSRFromMat(m)
+	csc := math.CSCFromMat(m)
+	if !csr.Mat().Eq(m) || !csc.Mat().Eq(m) || !csr.CSC().Mat().Eq(m) || !csc.CSR().Mat().Eq(m) {
+		t.Fatalf("inconsistent round trip")
+	}
+	if csr.NNZ() != csc.NNZ() || csr.Get(5, 7) != m.Get(5, 7) || csc.Get(5, 7) != m.Get(5, 7) {
+		t.Fatalf("inconsistent elements")
+	}
+
+	want := m.MulNaive(x)
+	for _, got := range [][]float32{csr.MulVec(x.Data), csc.MulVec(x.Data)} {
+		if v := (math.Mat[float32]{Row: 37, Col: 1, Data: got}); !v.Eq(want) {
+			t.Fatalf("MulVec: got %v, want %v", v, want)
+		}
+	}
+	want = m.MulNaive(d)
+	if got := csr.Mul(d); !got.Eq(want) {
+		t.Fatalf("CSR.Mul: got %v, want %v", got, want)
+	}
+	if got := csc.Mul(d.Slice(0, 23, 2, 9)); !got.Eq(m.MulNaive(d.Slice(0, 23, 2, 9))) {
+		t.Fatalf("CSC.Mul: different results compare to MulNaive")
+	}
+
+	// Duplicated triplets are summed regardless of their order.
+	s := math.NewCSR(2, 3, []int{1, 0, 1, 1}, []int{2, 1, 0, 2}, []int32{1, 2, 3, 4})
+	dense := math.Mat[int32]{Row: 2, Col: 3, Data: []int32{0, 2, 0, 3, 0, 5}}
+	if !s.Mat().Eq(dense) || s.NNZ() != 3 {
+		t.Fatalf("NewCSR: got %v, want %v", s.Mat(), dense)
+	}
+	c := math.NewCSC(2, 3, []int{1, 0, 1, 1}, []int{2, 1, 0, 2}, []int32{1, 2, 3, 4})
+	if !c.Mat().Eq(dense) || c.NNZ() != 3 {
+		t.Fatalf("NewCSC: got %v, want %v", c.Mat(), dense)
+	}
+}
+
+func TestBitMat(t *testing.T) {
+	// The sizes cross the word boundaries, and the counts of MulNaive
+	// on uint8 do not overflow.
+	m1, m2, m3 := randBits(37, 130, 1), randBits(130, 70, 2), randBits(37, 130, 3)
+	b1, b2, b3 := math.BitMatFromMat(m1), math.BitMatFromMat(m2), math.BitMatFromMat(m3)
+	if !b1.Mat().Eq(m1) || b1.Words() != 3 {
+		t.Fatalf("inconsistent round trip")
+	}
+
+	count := m1.MulNaive(m2)
+	want := count.Apply(func(v uint8) uint8 {
+		if v > 0 {
+			return 1
+		}
+		return 0
+	})
+	if got := b1.Mul(b2).Mat(); !got.Eq(want) {
+		t.Fatalf("Mul: got %v, want %v", got, want)
+	}
+	got := b1.MulCount(b2)
+	for i, v := range count.Data {
+		if got.Data[i] != int32(v) {
+			t.Fatalf("MulCount: got %v, want %v", got.Data[i], v)
+		}
+	}
+
+	logic := func(f func(a, b uint8) uint8) math.Mat[uint8] {
+		r := m1.Clone()
+		for i := range r.Data {
+			r.Data[i] = f(m1.Data[i], m3.Data[i])
+		}
+		return r
+	}
+	tests := []struct {
+		got  math.BitMat
+		want math.Mat[uint8]
+	}{
+		{b1.And(b3), logic(func(a, b uint8) uint8 { return a & b })},
+		{b1.Or(b3), logic(func(a, b uint8) uint8 { return a | b })},
+		{b1.Xor(b3), logic(func(a, b uint8) uint8 { return a ^ b })},
+		{b1.T(), m1.View().T().Clone()},
+	}
+	for i, tt := range tests {
+		if !tt.got.Mat().Eq(tt.want) {
+			t.Fatalf("#%d: got %v, want %v", i, tt.got.Mat(), tt.want)
+		}
+	}
+	ones := 0
+	for _, v := range m1.Data {
+		ones += int(v)
+	}
+	if b1.Count() != ones || b1.Xor(b1).Count() != 0 {
+		t.Fatalf("Count: got %v, want %v", b1.Count(), ones)
+	}
+
+	b1.Set(3, 100, true)
+	b1.Set(3, 101, false)
+	if !b1.Get(3, 100) || b1.Get(3, 101) {
+		t.Fatalf("Set: inconsistent elements")
+	}
+}
+
+// randBits returns a random matrix of zeros and ones from the seed.
+func randBits(row, col int, seed int64) math.Mat[uint8] {
+	m := math.NewRandMat[float32](row, col, math.WithSeed(seed))
+	r := math.Mat[uint8]{Row: row, Col: col, Data: make([]uint8, row*col)}
+	for i, v := range m.Data {
+		if v < 0.3 {
+			r.Data[i] = 1
+		}
+	}
+	return r
+}
+
+func TestRandMat(t *testing.T) {
+	// A large matrix is generated in parallel chunks, which must not
+	// depend on the number of CPUs.
+	m := math.NewRandMat[float32](300, 300, math.WithSeed(42))
+	procs := runtime.GOMAXPROCS(1)
+	same := math.NewRandMat[float32](300, 300, math.WithSeed(42))
+	runtime.GOMAXPROCS(procs)
+	if !m.Eq(same) || m.Eq(math.NewRandMat[float32](300, 300, math.WithSeed(43))) {
+		t.Fatalf("WithSeed: inconsistent generation")
+	}
+	src1, src2 := rand.NewSource(1), rand.NewSource(1)
+	if !math.NewRandMat[int32](3, 5, math.WithSource(src1)).Eq(math.NewRandMat[int32](3, 5, math.WithSource(src2))) {
+		t.Fatalf("WithSource: inconsistent generation")
+	}
+
+	in := func(m math.Mat[float64], lo, hi float64) bool {
+		return m.Min() >= lo && m.Max() < hi
+	}
+	if u := math.NewRandMat[float64](50, 50, math.Uniform(-3, -1), math.WithSeed(10)); !in(u, -3, -1) {
+		t.Fatalf("Uniform: elements out of range")
+	}
+	n := math.NewRandMat[float64](200, 500, math.Normal(5, 2), math.WithSeed(1))
+	mean := n.Mean()
+	std := stdmath.Sqrt(n.Apply(func(v float64) float64 { return (v - mean) * (v - mean) }).Mean())
+	if stdmath.Abs(mean-5) > 0.05 || stdmath.Abs(std-2) > 0.05 {
+		t.Fatalf("Normal: got mean %v and standard deviation %v", mean, std)
+	}
+
+	// Integer matrices are not all zeros, and their elements are
+	// within the range.
+	i := math.NewRandMat[int64](20, 20, math.IntRange(-5, 5), math.WithSeed(11))
+	if i.Min() < -5 || i.Max() >= 5 || i.Min() == i.Max() {
+		t.Fatalf("IntRange: unexpected range [%v, %v]", i.Min(), i.Max())
+	}
+	if u := math.NewRandMat[uint8](20, 20, math.WithSeed(12)); u.Max() == 0 || u.Max() >= 10 {
+		t.Fatalf("NewRandMat: unexpected integer maximum %v", u.Max())
+	}
+
+	id := math.Identity[float32](3)
+	d := math.Diag[float32](1, 1, 1)
+	if !id.Eq(d) || !id.Eq(math.Ones[float32](3, 1).Hadamard(id)) || math.Zeros[float32](2, 3).Sum() != 0 {
+		t.Fatalf("inconsistent constructors")
+	}
+	if got := math.Ones[complex64](2, 2).Sum(); got != 4 {
+		t.Fatalf("Ones: got sum %v, want 4", got)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	a := math.Mat[float32]{Row: 2, Col: 3, Data: []float32{1, 1000, -2, 0, 5, 7}}
+	b := a.Clone()
+	b.Data[1] = stdmath.Nextafter32(1000, 2000) // 1 ulp, 6e-5 absolute
+	b.Data[4] = 5.001
+	b.Data[5] = float32(stdmath.NaN())
+
+	r := math.Compare(a, b, math.CompareOptions{Abs: 1e-4})
+	if r.Mismatches != 2 || len(r.Positions) != 2 || r.Positions[0] != [2]int{1, 1} || r.Positions[1] != [2]int{1, 2} {
+		t.Fatalf("Abs: unexpected mismatches %v", r)
+	}
+	if r.NaN != 1 || r.Inf != 0 || r.MaxAbsAt != [2]int{1, 1} || stdmath.Abs(r.MaxAbsErr-1e-3) > 1e-6 {
+		t.Fatalf("Abs: unexpected report %v", r)
+	}
+	if r.MaxULP < 1 || r.MaxRelAt != [2]int{1, 1} {
+		t.Fatalf("Abs: unexpected report %v", r)
+	}
+
+	b.Data[5] = 7
+	if r := math.Compare(a, b, math.CompareOptions{ULP: 1}); r.Mismatches != 1 || r.Positions[0] != [2]int{1, 1} {
+		t.Fatalf("ULP: unexpected report %v", r)
+	}
+	if r := math.Compare(a, b, math.CompareOptions{Rel: 1e-3}); !r.Equal() {
+		t.Fatalf("Rel: unexpected report %v", r)
+	}
+
+	// Equal infinities match, and different infinities do not.
+	c := math.Mat[float64]{Row: 1, Col: 2, Data: []float64{stdmath.Inf(1), stdmath.Inf(1)}}
+	d := math.Mat[float64]{Row: 1, Col: 2, Data: []float64{stdmath.Inf(1), stdmath.Inf(-1)}}
+	if r := math.Compare(c, d, math.CompareOptions{Rel: 1}); r.Mismatches != 1 || r.Inf != 2 {
+		t.Fatalf("Inf: unexpected report %v", r)
+	}
+
+	// The relative tolerance is meaningful for large sums, where an
+	// absolute tolerance is not.
+	m1 := math.NewRandMat[float32](64, 512, math.WithSeed(1))
+	m2 := math.NewRandMat[float32](512, 64, math.WithSeed(2))
+	if r := math.Compare(m1.Mul(m2), m1.MulNaive(m2), math.CompareOptions{Rel: 1e-5}); !r.Equal() {
+		t.Fatalf("Rel: %v", r)
+	}
+
+	x := math.Mat[int32]{Row: 1, Col: 2, Data: []int32{3, -4}}
+	y := math.Mat[int32]{Row: 1, Col: 2, Data: []int32{5, -4}}
+	if r := math.Compare(x, y, math.CompareOptions{ULP: 1}); r.Mismatches != 1 || r.MaxULP != 2 {
+		t.Fatalf("int32: unexpected report %v", r)
+	}
+}
@@ -0,0 +1,263 @@
+// Copyright 2023 Changkun Ou <changkun.de>. All rights reserved.
+// Use of this source code is governed by a MIT license that
+// can be found in the LICENSE file.
+
+package nn
+
+import (
+	stdmath "math"
+
+	"changkun.de/x/gogpu/autodiff"
+	"changkun.de/x/gogpu/gpu"
+	"changkun.de/x/gogpu/math"
+)
+
+// Option configures the tapes of NewTape and Predict.
+type Option func(c *config)
+
+type config struct {
+	mul math.MulFunc[float32]
+}
+
+// WithMul multiplies the matrices of the forward and the backward pass
+// by mul, e.g. math.Mat[float32].Mul to run on the CPU.
+func WithMul(mul math.MulFunc[float32]) Option {
+	return func(c *config) { c.mul = mul }
+}
+
+// NewTape returns a tape whose multiplications run by gpu.Mul, hence on
+// the GPU device if one is available and on the CPU otherwise, unless
+// WithMul configures another multiplication.
+func NewTape(opts ...Option) *autodiff.Tape[float32] {
+	c := config{mul: gpu.Mul[float32]}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return &autodiff.Tape[float32]{Mul: c.mul}
+}
+
+// Param is a trainable parameter of a layer.
+type Param struct {
+	Value math.Mat[float32]
+
+	tape *autodiff.Tape[float32]
+	vars []*autodiff.Var[float32]
+}
+
+// Var records the parameter as a leaf of the tape, whose gradient is
+// read by Grad after the backward pass. A parameter may be recorded
+// more than once on a tape, e.g. by a layer that is used twice, and
+// recording it on another tape discards the previous ones. A tape
+// without gradients, such as that of Predict, does not record it.
+func (p *Param) Var(t *autodiff.Tape[float32]) *autodiff.Var[float32] {
+	v := t.Var(p.Value)
+	if t.NoGrad {
+		return v
+	}
+	if p.tape != t {
+		p.tape, p.vars = t, nil
+	}
+	p.vars = append(p.vars, v)
+	return v
+}
+
+// Grad returns the gradient of the parameter of the last backward pass
+// of the tape on which it was recorded, which is the sum of the
+// gradients of all its uses.
+func (p *Param) Grad() math.Mat[float32] {
+	g := math.Zeros[float32](p.Value.Row, p.Value.Col)
+	for _, v := range p.vars {
+		for k, d := range v.Grad.Data {
+			g.Data[k] += d
+		}
+	}
+	return g
+}
+
+// Layer is a layer of a network. The input of a layer is a batch of
+// samples, one in each row.
+type Layer interface {
+	// Forward records the output of the layer for the input x.
+	Forward(t *autodiff.Tape[float32], x *autodiff.Var[float32]) *autodiff.Var[float32]
+	// Params returns the trainable parameters of the layer.
+	Params() []*Param
+}
+
+// Sequential is a network that applies its layers in order.
+type Sequential []Layer
+
+// Forward records the output of the last layer.
+func (s Sequential) Forward(t *autodiff.Tape[float32], x *autodiff.Var[float32]) *autodiff.Var[float32] {
+	for _, l := range s {
+		x = l.Forward(t, x)
+	}
+	return x
+}
+
+// Params returns the parameters of all layers.
+func (s Sequential) Params() []*Param {
+	var ps []*Param
+	for _, l := range s {
+		ps = append(ps, l.Params()...)
+	}
+	return ps
+}
+
+// Predict returns the output of the network for the batch x, without
+// keeping the tape for a backward pass. The options configure the tape
+// as those of NewTape. The tape has no gradients, hence a prediction
+// between a backward pass and the optimizer step keeps the gradients
+// of the parameters.
+func Predict(l Layer, x math.Mat[float32], opts ...Option) math.Mat[float32] {
+	t := NewTape(opts...)
+	t.NoGrad = true
+	return l.Forward(t, t.Var(x)).Value
+}
+
+// Dense is a fully connected layer that computes x*W + B.
+type Dense struct {
+	W Param // in x out weights
+	B Param // 1 x out bias
+}
+
+// NewDense returns a dense layer whose weights are drawn from the
+// Glorot uniform distribution and whose bias is zero. The options,
+// e.g. math.WithSeed, configure the random weights.
+func NewDense(in, out int, opts ...math.RandOption) *Dense {
+	l := stdmath.Sqrt(6 / float64(in+out))
+	opts = append([]math.RandOption{math.Uniform(-l, l)}, opts...)
+	return &Dense{
+		W: Param{Value: math.NewRandMat[float32](in, out, opts...)},
+		B: Param{Value: math.Zeros[float32](1, out)},
+	}
+}
+
+func (d *Dense) Forward(t *autodiff.Tape[float32], x *autodiff.Var[float32]) *autodiff.Var[float32] {
+	return x.Mul(d.W.Var(t)).Add(d.B.Var(t))
+}
+
+func (d *Dense) Params() []*Param { return []*Param{&d.W, &d.B} }
+
+// ReLU is the rectified linear unit max(0, x).
+type ReLU struct{}
+
+func (ReLU) Forward(t *autodiff.Tape[float32], x *autodiff.Var[float32]) *autodiff.Var[float32] {
+	return x.ReLU()
+}
+
+func (ReLU) Params() []*Param { return nil }
+
+// GELU is the Gaussian error linear unit x*Φ(x), where Φ is the
+// cumulative distribution function of the standard normal distribution
+//
+//	x/2 * (1 + erf(x/sqrt(2)))
+//
+// which is the default of PyTorch. If Tanh is true, it computes the tanh
+// approximation of PyTorch's approximate="tanh" instead
+//
+//	x/2 * (1 + tanh(sqrt(2/π) * (x + 0.044715x³)))
+type GELU struct {
+	Tanh bool
+}
+
+func (l GELU) Forward(t *autodiff.Tape[float32], x *autodiff.Var[float32]) *autodiff.Var[float32] {
+	if !l.Tanh {
+		const (
+			s = 0.7071067811865476 // 1/sqrt(2)
+			p = 0.3989422804014327 // 1/sqrt(2π)
+		)
+		return x.Apply(func(x float32) float32 {
+			v := float64(x)
+			return float32(0.5 * v * (1 + stdmath.Erf(v*s)))
+		}, func(x, y float32) float32 {
+			v := float64(x)
+			return float32(0.5*(1+stdmath.Erf(v*s)) + v*p*stdmath.Exp(-0.5*v*v))
+		})
+	}
+
+	const c = 0.7978845608028654 // sqrt(2/π)
+	th := func(x float64) float64 { return stdmath.Tanh(c * (x + 0.044715*x*x*x)) }
+	return x.Apply(func(x float32) float32 {
+		return float32(0.5 * float64(x) * (1 + th(float64(x))))
+	}, func(x, y float32) float32 {
+		v := float64(x)
+		h := th(v)
+		return float32(0.5*(1+h) + 0.5*v*(1-h*h)*c*(1+3*0.044715*v*v))
+	})
+}
+
+func (GELU) Params() []*Param { return nil }
+
+// Softmax normalizes every row to a probability distribution.
+type Softmax struct{}
+
+func (Softmax) Forward(t *autodiff.Tape[float32], x *autodiff.Var[float32]) *autodiff.Var[float32] {
+	y := softmax(x.Value)
+	return t.Op(y, func(g math.Mat[float32]) []math.Mat[float32] {
+		// The gradient of a row is y∘(g - <g, y>).
+		d := math.Mat[float32]{Row: y.Row, Col: y.Col, Data: make([]float32, len(y.Data))}
+		for i := 0; i < y.Row; i++ {
+			var dot float32
+			for j := 0; j < y.Col; j++ {
+				dot += g.Get(i, j) * y.Get(i, j)
+			}
+			for j := 0; j < y.Col; j++ {
+				d.Data[i*y.Col+j] = y.Get(i, j) * (g.Get(i, j) - dot)
+			}
+		}
+		return []math.Mat[float32]{d}
+	}, x)
+}
+
+func (Softmax) Params() []*Param { return nil }
+
+// LayerNorm normalizes every row to zero mean and unit variance, then
+// scales and shifts it by the trainable Gamma and Beta.
+type LayerNorm struct {
+	Gamma Param // 1 x dim scale
+	Beta  Param // 1 x dim shift
+	Eps   float32
+}
+
+// NewLayerNorm returns a layer normalization of rows of length dim,
+// which starts as the identity of normalized rows.
+func NewLayerNorm(dim int) *LayerNorm {
+	return &LayerNorm{
+		Gamma: Param{Value: math.Ones[float32](1, dim)},
+		Beta:  Param{Value: math.Zeros[float32](1, dim)},
+		Eps:   1e-5,
+	}
+}
+
+func (l *LayerNorm) Forward(t *autodiff.Tape[float32], x *autodiff.Var[float32]) *autodiff.Var[float32] {
+	xc := x.Sub(x.MeanAxis(1))
+	inv := xc.Hadamard(xc).MeanAxis(1).Apply(func(v float32) float32 {
+		return float32(1 / stdmath.Sqrt(float64(v+l.Eps)))
+	}, func(v, y float32) float32 { return -0.5 * y * y * y })
+	return xc.Hadamard(inv).Hadamard(l.Gamma.Var(t)).Add(l.Beta.Var(t))
+}
+
+func (l *LayerNorm) Params() []*Param { return []*Param{&l.Gamma, &l.Beta} }
+
+// softmax returns the row-wise softmax of m, which subtracts the
+// maximum of each row for numerical stability.
+func softmax(m math.Mat[float32]) math.Mat[float32] {
+	r := math.Mat[float32]{Row: m.Row, Col: m.Col, Data: make([]float32, m.Row*m.Col)}
+	if m.Col == 0 {
+		return r
+	}
+	for i := 0; i < m.Row; i++ {
+		row := m.Slice(i, i+1, 0, m.Col)
+		max := row.Max()
+		var sum float64
+		for j := 0; j < m.Col; j++ {
+			e := stdmath.Exp(float64(m.Get(i, j) - max))
+			r.Data[i*m.Col+j] = float32(e)
+			sum += e
+		}
+		for j := 0; j < m.Col; j++ {
+			r.Data[i*m.Col+j] /= float32(sum)
+		}
+	}
+	return r
+}
@@ -0,0 +1,65 @@
+// Copyright 2023 Changkun Ou <changkun.de>. All rights reserved.
+// Use of this source code is governed by a MIT license that
+// can be found in the LICENSE file.
+
+package nn
+
+import (
+	"fmt"
+	stdmath "math"
+
+	"changkun.de/x/gogpu/autodiff"
+	"changkun.de/x/gogpu/math"
+)
+
+// CrossEntropy records the mean cross-entropy loss between the softmax
+// of the rows of logits and the class labels, one for each row, as a
+// 1x1 matrix. The softmax is fused into the loss, hence logits are the
+// output of the last layer before a softmax.
+func CrossEntropy(logits *autodiff.Var[float32], labels []int) *autodiff.Var[float32] {
+	x := logits.Value
+	if len(labels) != x.Row {
+		panic(fmt.Sprintf("nn: %d labels for %d samples", len(labels), x.Row))
+	}
+	for _, c := range labels {
+		if c < 0 || c >= x.Col {
+			panic(fmt.Sprintf("nn: label %d out of %d classes", c, x.Col))
+		}
+	}
+
+	p := softmax(x)
+	var loss float64
+	for i, c := range labels {
+		loss -= stdmath.Log(stdmath.Max(float64(p.Get(i, c)), 1e-30))
+	}
+	n := float32(x.Row)
+	value := math.Mat[float32]{Row: 1, Col: 1, Data: []float32{float32(loss) / n}}
+
+	t := logits.Tape()
+	return t.Op(value, func(g math.Mat[float32]) []math.Mat[float32] {
+		// The gradient of a row is (softmax - onehot) / n.
+		d := p.Clone()
+		for i, c := range labels {
+			d.Data[i*d.Col+c] -= 1
+		}
+		return []math.Mat[float32]{d.Scale(g.Data[0] / n)}
+	}, logits)
+}
+
+// Accuracy returns the fraction of the rows of logits whose maximum is
+// at the column of the label.
+func Accuracy(logits math.Mat[float32], labels []int) float64 {
+	if len(labels) != logits.Row {
+		panic(fmt.Sprintf("nn: %d labels for %d samples", len(labels), logits.Row))
+	}
+	if logits.Row == 0 {
+		return 0
+	}
+	correct := 0
+	for i, c := range logits.ArgMaxAxis(1) {
+		if c == labels[i] {
+			correct++
+		}
+	}
+	return float64(correct) / float64(logits.Row)
+}
@@ -0,0 +1,189 @@
+// Copyright 2023 Changkun Ou <changkun.de>. All rights reserved.
+// Use of this source code is governed by a MIT license that
+// can be found in the LICENSE file.
+
+package nn
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"changkun.de/x/gogpu/math"
+)
+
+// ErrModel is returned if a model file is malformed or its weights do
+// not match its graph.
+var ErrModel = errors.New("nn: invalid model")
+
+// Model is a network of a sequence of layers loaded by Load, whose
+// input has a fixed number of features.
+//
+// A model is stored as a JSON graph that describes the layers, and a
+// NumPy .npz archive of float32 arrays that holds their weights by
+// name. The graph of a model with 4 input features reads as follows:
+//
+//	{
+//	  "inputs": 4,
+//	  "layers": [
+//	    {"type": "dense", "weight": "fc1.weight", "bias": "fc1.bias"},
+//	    {"type": "relu"},
+//	    {"type": "layernorm", "gamma": "ln.weight", "beta": "ln.bias", "eps": 1e-5},
+//	    {"type": "dense", "weight": "fc2.weight", "transpose": true},
+//	    {"type": "softmax"}
+//	  ]
+//	}
+//
+// The layer types are dense, relu, gelu, layernorm and softmax. The
+// weight of a dense layer has the shape (in, out), or (out, in) as in
+// PyTorch if transpose is true, and its bias has the shape (out,) and
+// is optional. The gamma and beta of a layer normalization have the
+// shape of its input features, and eps defaults to 1e-5. The approximate
+// field of a gelu is "none", the default, or "tanh" as in PyTorch.
+type Model struct {
+	Sequential
+
+	Inputs  int // number of input features
+	Outputs int // number of output features
+}
+
+// graph is the JSON description of a model.
+type graph struct {
+	Inputs int `json:"inputs"`
+	Layers []struct {
+		Type        string   `json:"type"`
+		Weight      string   `json:"weight"`
+		Bias        string   `json:"bias"`
+		Transpose   bool     `json:"transpose"`
+		Gamma       string   `json:"gamma"`
+		Beta        string   `json:"beta"`
+		Eps         *float32 `json:"eps"`
+		Approximate string   `json:"approximate"`
+	} `json:"layers"`
+}
+
+// LoadFiles loads a model from the JSON graph and the .npz weights at
+// the given paths, see Load.
+func LoadFiles(graphPath, weightsPath string) (*Model, error) {
+	g, err := os.Open(graphPath)
+	if err != nil {
+		return nil, err
+	}
+	defer g.Close()
+	w, err := os.Open(weightsPath)
+	if err != nil {
+		return nil, err
+	}
+	defer w.Close()
+	fi, err := w.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return Load(g, w, fi.Size())
+}
+
+// Load loads a model from its JSON graph and its .npz weights of the
+// given size, see Model for the format. The shapes of all weights are
+// validated against the graph.
+func Load(graphJSON io.Reader, weights io.ReaderAt, size int64) (*Model, error) {
+	var g graph
+	d := json.NewDecoder(graphJSON)
+	d.DisallowUnknownFields()
+	if err := d.Decode(&g); err != nil {
+		return nil, fmt.Errorf("%w: graph: %v", ErrModel, err)
+	}
+	if g.Inputs <= 0 {
+		return nil, fmt.Errorf("%w: graph: inputs must be positive, got %d", ErrModel, g.Inputs)
+	}
+	arrays, err := math.ReadNPZ[float32](weights, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: weights: %v", ErrModel, err)
+	}
+
+	m := &Model{Inputs: g.Inputs}
+	width := g.Inputs
+	for i, l := range g.Layers {
+		errorf := func(format string, args ...any) error {
+			return fmt.Errorf("%w: layer %d (%s): %s", ErrModel, i, l.Type, fmt.Sprintf(format, args...))
+		}
+		array := func(name string) (math.Mat[float32], error) {
+			if name == "" {
+				return math.Mat[float32]{}, errorf("missing array name")
+			}
+			a, ok := arrays[name]
+			if !ok {
+				return math.Mat[float32]{}, errorf("array %q not found in weights", name)
+			}
+			return a, nil
+		}
+		vector := func(name string, n int) (math.Mat[float32], error) {
+			a, err := array(name)
+			if err == nil && (a.Row != 1 || a.Col != n) {
+				err = errorf("array %q has shape %dx%d, want %d elements", name, a.Row, a.Col, n)
+			}
+			return a, err
+		}
+
+		switch l.Type {
+		case "dense":
+			w, err := array(l.Weight)
+			if err != nil {
+				return nil, err
+			}
+			if !l.Transpose && w.Row != width {
+				return nil, errorf("weight %q has shape %dx%d, want %d rows", l.Weight, w.Row, w.Col, width)
+			}
+			if l.Transpose {
+				if w.Col != width {
+					return nil, errorf("weight %q has shape %dx%d, want %d columns", l.Weight, w.Row, w.Col, width)
+				}
+				w = w.View().T().Clone()
+			}
+			dense := &Dense{W: Param{Value: w}, B: Param{Value: math.Zeros[float32](1, w.Col)}}
+			if l.Bias != "" {
+				if dense.B.Value, err = vector(l.Bias, w.Col); err != nil {
+					return nil, err
+				}
+			}
+			m.Sequential = append(m.Sequential, dense)
+			width = w.Col
+		case "layernorm":
+			ln := NewLayerNorm(width)
+			if ln.Gamma.Value, err = vector(l.Gamma, width); err != nil {
+				return nil, err
+			}
+			if ln.Beta.Value, err = vector(l.Beta, width); err != nil {
+				return nil, err
+			}
+			if l.Eps != nil {
+				ln.Eps = *l.Eps
+			}
+			m.Sequential = append(m.Sequential, ln)
+		case "relu":
+			m.Sequential = append(m.Sequential, ReLU{})
+		case "gelu":
+			if l.Approximate != "" && l.Approximate != "none" && l.Approximate != "tanh" {
+				return nil, errorf("unknown approximation %q", l.Approximate)
+			}
+			m.Sequential = append(m.Sequential, GELU{Tanh: l.Approximate == "tanh"})
+		case "softmax":
+			m.Sequential = append(m.Sequential, Softmax{})
+		default:
+			return nil, errorf("unknown layer type")
+		}
+	}
+	m.Outputs = width
+	return m, nil
+}
+
+// Predict runs the model on a batch of samples, one in each row, and
+// returns the outputs in the same order. The multiplications run by
+// gpu.Mul unless WithMul configures another multiplication.
+func (m *Model) Predict(x math.Mat[float32], opts ...Option) (math.Mat[float32], error) {
+	if x.Col != m.Inputs {
+		return math.Mat[float32]{}, fmt.Errorf("nn: input has %d features, model expects %d", x.Col, m.Inputs)
+	}
+	return Predict(m.Sequential, x, opts...), nil
+}
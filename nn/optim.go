@@ -0,0 +1,104 @@
+// Copyright 2023 Changkun Ou <changkun.de>. All rights reserved.
+// Use of this source code is governed by a MIT license that
+// can be found in the LICENSE file.
+
+package nn
+
+import (
+	stdmath "math"
+
+	"changkun.de/x/gogpu/math"
+)
+
+// Optimizer updates parameters in place from their gradients.
+type Optimizer interface {
+	// Step updates the parameters by one step of their gradients of the
+	// last backward pass.
+	Step(params []*Param)
+}
+
+// SGD is the stochastic gradient descent with an optional momentum.
+type SGD struct {
+	LR       float32 // learning rate
+	Momentum float32 // weight of the previous update, zero for none
+
+	velocity map[*Param]math.Mat[float32]
+}
+
+func (o *SGD) Step(params []*Param) {
+	if o.velocity == nil {
+		o.velocity = map[*Param]math.Mat[float32]{}
+	}
+	for _, p := range params {
+		g := p.Grad()
+		if o.Momentum == 0 {
+			update(p.Value, func(k int, w float32) float32 { return w - o.LR*g.Data[k] })
+			continue
+		}
+
+		v, ok := o.velocity[p]
+		if !ok {
+			v = math.Zeros[float32](p.Value.Row, p.Value.Col)
+			o.velocity[p] = v
+		}
+		update(p.Value, func(k int, w float32) float32 {
+			v.Data[k] = o.Momentum*v.Data[k] + g.Data[k]
+			return w - o.LR*v.Data[k]
+		})
+	}
+}
+
+// Adam is the Adam optimizer with bias-corrected estimates of the first
+// and the second moments of the gradients.
+type Adam struct {
+	LR    float32 // learning rate
+	Beta1 float32 // decay of the first moment
+	Beta2 float32 // decay of the second moment
+	Eps   float32 // added to the root of the second moment
+
+	step int
+	m, v map[*Param]math.Mat[float32]
+}
+
+// NewAdam returns an Adam optimizer of the given learning rate and the
+// usual decays 0.9 and 0.999.
+func NewAdam(lr float32) *Adam {
+	return &Adam{LR: lr, Beta1: 0.9, Beta2: 0.999, Eps: 1e-8}
+}
+
+func (o *Adam) Step(params []*Param) {
+	if o.m == nil {
+		o.m, o.v = map[*Param]math.Mat[float32]{}, map[*Param]math.Mat[float32]{}
+	}
+	o.step++
+	c1 := 1 - stdmath.Pow(float64(o.Beta1), float64(o.step))
+	c2 := 1 - stdmath.Pow(float64(o.Beta2), float64(o.step))
+	for _, p := range params {
+		g := p.Grad()
+		m, ok := o.m[p]
+		if !ok {
+			m = math.Zeros[float32](p.Value.Row, p.Value.Col)
+			o.m[p] = m
+			o.v[p] = math.Zeros[float32](p.Value.Row, p.Value.Col)
+		}
+		v := o.v[p]
+		update(p.Value, func(k int, w float32) float32 {
+			gk := g.Data[k]
+			m.Data[k] = o.Beta1*m.Data[k] + (1-o.Beta1)*gk
+			v.Data[k] = o.Beta2*v.Data[k] + (1-o.Beta2)*gk*gk
+			mh := float64(m.Data[k]) / c1
+			vh := float64(v.Data[k]) / c2
+			return w - float32(float64(o.LR)*mh/(stdmath.Sqrt(vh)+float64(o.Eps)))
+		})
+	}
+}
+
+// update sets every element of w to f of its dense index k and its
+// value, where k indexes the gradient and the optimizer state.
+func update(w math.Mat[float32], f func(k int, w float32) float32) {
+	for i := 0; i < w.Row; i++ {
+		for j := 0; j < w.Col; j++ {
+			w.Set(i, j, f(i*w.Col+j, w.Get(i, j)))
+		}
+	}
+}
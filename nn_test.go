@@ -0,0 +1,240 @@
+// Copyright 2023 Changkun Ou <changkun.de>. All rights reserved.
+// Use of this source code is governed by a MIT license that
+// can be found in the LICENSE file.
+
+package main_test
+
+import (
+	"bytes"
+	"errors"
+	stdmath "math"
+	"math/rand"
+	"os"
+	"strings"
+	"testing"
+
+	"changkun.de/x/gogpu/autodiff"
+	"changkun.de/x/gogpu/math"
+	"changkun.de/x/gogpu/nn"
+)
+
+func TestLayerGrad(t *testing.T) {
+	x := math.NewRandMat[float32](4, 5, math.Normal(0, 1), math.WithSeed(1))
+	labels := []int{0, 4, 2, 2}
+	shared := nn.NewDense(5, 5, math.WithSeed(6))
+
+	for _, tt := range []struct {
+		name  string
+		layer nn.Layer
+	}{
+		{"Dense", nn.NewDense(5, 3, math.WithSeed(2))},
+		{"ReLU", nn.ReLU{}},
+		{"GELU", nn.GELU{}},
+		{"GELU tanh", nn.GELU{Tanh: true}},
+		{"Softmax", nn.Sequential{nn.NewDense(5, 5, math.WithSeed(3)), nn.Softmax{}, nn.NewDense(5, 2, math.WithSeed(4))}},
+		{"LayerNorm", nn.Sequential{nn.NewLayerNorm(5), nn.NewDense(5, 2, math.WithSeed(5))}},
+		{"Shared", nn.Sequential{shared, nn.GELU{}, shared}},
+	} {
+		f := func(tape *autodiff.Tape[float32], vs []*autodiff.Var[float32]) *autodiff.Var[float32] {
+			return tt.layer.Forward(tape, vs[0]).Tanh()
+		}
+		if err := autodiff.CheckGrad(f, []math.Mat[float32]{x}, 1e-2, 2e-2); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		checkParamGrad(t, tt.name, tt.layer, x)
+	}
+
+	loss := func(tape *autodiff.Tape[float32], vs []*autodiff.Var[float32]) *autodiff.Var[float32] {
+		return nn.CrossEntropy(vs[0], labels)
+	}
+	if err := autodiff.CheckGrad(loss, []math.Mat[float32]{x}, 1e-2, 2e-2); err != nil {
+		t.Errorf("CrossEntropy: %v", err)
+	}
+}
+
+// checkParamGrad compares the gradients of the parameters of a layer
+// with central finite differences.
+func checkParamGrad(t *testing.T, name string, l nn.Layer, x math.Mat[float32]) {
+	t.Helper()
+
+	sum := func() float64 {
+		tape := nn.NewTape()
+		s := 0.0
+		for _, v := range l.Forward(tape, tape.Var(x)).Tanh().Value.Data {
+			s += float64(v)
+		}
+		return s
+	}
+	tape := nn.NewTape()
+	l.Forward(tape, tape.Var(x)).Tanh().Backward()
+	nn.Predict(l, x) // keeps the gradients
+	var grads []math.Mat[float32]
+	for _, p := range l.Params() {
+		grads = append(grads, p.Grad())
+	}
+
+	const eps = 1e-2
+	for i, p := range l.Params() {
+		g := grads[i]
+		for k, w := range p.Value.Data {
+			p.Value.Data[k] = w + eps
+			plus := sum()
+			p.Value.Data[k] = w - eps
+			minus := sum()
+			p.Value.Data[k] = w
+
+			want := (plus - minus) / (2 * eps)
+			if got := float64(g.Data[k]); stdmath.Abs(got-want) > 2e-2*stdmath.Max(1, stdmath.Abs(want)) {
+				t.Errorf("%s: gradient of parameter %d at %d is %v, finite difference is %v", name, i, k, got, want)
+				return
+			}
+		}
+	}
+}
+
+// xorData returns points in [-1, 1]² whose class is whether both
+// coordinates have the same sign, which is not linearly separable.
+func xorData(n int, seed int64) (math.Mat[float32], []int) {
+	r := rand.New(rand.NewSource(seed))
+	x := math.Zeros[float32](n, 2)
+	labels := make([]int, n)
+	for i := 0; i < n; i++ {
+		a, b := r.Float32()*2-1, r.Float32()*2-1
+		x.Set(i, 0, a)
+		x.Set(i, 1, b)
+		if a*b > 0 {
+			labels[i] = 1
+		}
+	}
+	return x, labels
+}
+
+func TestMLP(t *testing.T) {
+	train, trainLabels := xorData(512, 1)
+	test, testLabels := xorData(256, 2)
+
+	for _, tt := range []struct {
+		name string
+		opt  nn.Optimizer
+	}{
+		{"SGD", &nn.SGD{LR: 0.1, Momentum: 0.9}},
+		{"Adam", nn.NewAdam(0.01)},
+	} {
+		model := nn.Sequential{
+			nn.NewDense(2, 32, math.WithSeed(1)),
+			nn.GELU{},
+			nn.NewLayerNorm(32),
+			nn.NewDense(32, 32, math.WithSeed(2)),
+			nn.ReLU{},
+			nn.NewDense(32, 2, math.WithSeed(3)),
+		}
+
+		// The multiplications run on the CPU, whose results do not
+		// depend on the device.
+		cpu := nn.WithMul(math.Mat[float32].Mul)
+		var loss float32
+		for step := 0; step < 200; step++ {
+			tape := nn.NewTape(cpu)
+			l := nn.CrossEntropy(model.Forward(tape, tape.Var(train)), trainLabels)
+			l.Backward()
+			tt.opt.Step(model.Params())
+			loss = l.Value.Data[0]
+		}
+		if stdmath.IsNaN(float64(loss)) || loss > 0.2 {
+			t.Errorf("%s: training loss %v", tt.name, loss)
+		}
+		if acc := nn.Accuracy(nn.Predict(model, test, cpu), testLabels); acc < 0.95 {
+			t.Errorf("%s: test accuracy %v, want at least 0.95", tt.name, acc)
+		}
+
+		probs := nn.Predict(append(model, nn.Softmax{}), test, cpu)
+		if r := math.Compare(probs.SumAxis(1), math.Ones[float32](256, 1), math.CompareOptions{Abs: 1e-5}); !r.Equal() {
+			t.Errorf("%s: softmax rows do not sum to one: %v", tt.name, r)
+		}
+	}
+}
+
+func TestLoadModel(t *testing.T) {
+	model, err := nn.LoadFiles("testdata/mlp/graph.json", "testdata/mlp/weights.npz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if model.Inputs != 3 || model.Outputs != 2 || len(model.Sequential) != 8 {
+		t.Fatalf("got %d inputs, %d outputs and %d layers", model.Inputs, model.Outputs, len(model.Sequential))
+	}
+
+	b, err := os.ReadFile("testdata/mlp/golden.npz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden, err := math.ReadNPZ[float32](bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cpu := nn.WithMul(math.Mat[float32].Mul)
+	got, err := model.Predict(golden["input"], cpu)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := math.Compare(got, golden["output"], math.CompareOptions{Abs: 1e-6}); !r.Equal() {
+		t.Fatalf("golden output: %v", r)
+	}
+
+	// Every sample of a batch is independent of the others.
+	for i := 0; i < golden["input"].Row; i++ {
+		got, _ := model.Predict(golden["input"].Slice(i, i+1, 0, 3), cpu)
+		if r := math.Compare(got, golden["output"].Slice(i, i+1, 0, 2), math.CompareOptions{Abs: 1e-6}); !r.Equal() {
+			t.Fatalf("sample %d: %v", i, r)
+		}
+	}
+
+	if _, err := model.Predict(math.Zeros[float32](2, 4)); err == nil || !strings.Contains(err.Error(), "4 features, model expects 3") {
+		t.Fatalf("want an error for the input shape, got %v", err)
+	}
+}
+
+func TestLoadModelErrors(t *testing.T) {
+	weights := map[string]math.Mat[float32]{
+		"w":  math.Zeros[float32](3, 4),
+		"b":  math.Zeros[float32](1, 4),
+		"ln": math.Zeros[float32](1, 4),
+	}
+	var buf bytes.Buffer
+	if err := math.WriteNPZ(&buf, weights); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		graph, err string
+	}{
+		{`{"inputs": 3, "layers": [{"type": "dense", "weight": "w", "bias": "b"}]}`, ""},
+		{`{"inputs": 4, "layers": [{"type": "dense", "weight": "w", "transpose": true}]}`, ""},
+		{`{"inputs": 3, "layers": [`, "graph"},
+		{`{"inputs": 3, "layers": [], "outputs": 4}`, "unknown field"},
+		{`{"layers": []}`, "inputs must be positive"},
+		{`{"inputs": 3, "layers": [{"type": "conv"}]}`, "layer 0 (conv): unknown layer type"},
+		{`{"inputs": 3, "layers": [{"type": "gelu", "approximate": "erf"}]}`, `layer 0 (gelu): unknown approximation "erf"`},
+		{`{"inputs": 3, "layers": [{"type": "dense"}]}`, "layer 0 (dense): missing array name"},
+		{`{"inputs": 3, "layers": [{"type": "dense", "weight": "v"}]}`, `array "v" not found`},
+		{`{"inputs": 4, "layers": [{"type": "dense", "weight": "w"}]}`, `weight "w" has shape 3x4, want 4 rows`},
+		{`{"inputs": 3, "layers": [{"type": "dense", "weight": "w", "transpose": true}]}`, `weight "w" has shape 3x4, want 3 columns`},
+		{`{"inputs": 3, "layers": [{"type": "dense", "weight": "w", "bias": "w"}]}`, `array "w" has shape 3x4, want 4 elements`},
+		{`{"inputs": 3, "layers": [{"type": "relu"}, {"type": "layernorm", "gamma": "ln", "beta": "ln"}]}`,
+			`layer 1 (layernorm): array "ln" has shape 1x4, want 3 elements`},
+	} {
+		_, err := nn.Load(strings.NewReader(tt.graph), bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		if tt.err == "" {
+			if err != nil {
+				t.Errorf("%s: %v", tt.graph, err)
+			}
+			continue
+		}
+		if !errors.Is(err, nn.ErrModel) || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: want an error containing %q, got %v", tt.graph, tt.err, err)
+		}
+	}
+
+	if _, err := nn.Load(strings.NewReader(`{"inputs": 1, "layers": []}`), strings.NewReader("not a zip"), 9); !errors.Is(err, nn.ErrModel) {
+		t.Errorf("invalid weights: want ErrModel, got %v", err)
+	}
+}
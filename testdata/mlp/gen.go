@@ -0,0 +1,102 @@
+// Copyright 2023 Changkun Ou <changkun.de>. All rights reserved.
+// Use of this source code is governed by a MIT license that
+// can be found in the LICENSE file.
+
+//go:build ignore
+
+// This program generates weights.npz and golden.npz of the model in
+// graph.json. The golden output is computed by an independent float64
+// implementation of the layers rather than by package nn, hence the
+// test of the loader does not compare nn with itself.
+//
+// Run it from the root of the repository:
+//
+//	go run testdata/mlp/gen.go
+package main
+
+import (
+	stdmath "math"
+	"os"
+
+	"changkun.de/x/gogpu/math"
+)
+
+func mat(row, col int, v ...float32) math.Mat[float32] {
+	return math.Mat[float32]{Row: row, Col: col, Data: v}
+}
+
+func main() {
+	w := map[string]math.Mat[float32]{
+		// The layout of PyTorch, (out, in).
+		"fc1.weight": mat(4, 3, 0.5, -0.25, 0.75, -1, 0.5, 0.25, 0.3, 0.9, -0.6, 0.1, -0.2, 0.4),
+		"fc1.bias":   mat(1, 4, 0.1, -0.1, 0.2, 0),
+		"ln.weight":  mat(1, 4, 1, 0.5, 1.5, -1),
+		"ln.bias":    mat(1, 4, 0, 0.25, -0.25, 0.5),
+		"fc2.weight": mat(4, 5, 0.2, -0.4, 0.6, 0.1, -0.3, 0.5, 0.3, -0.2, 0.7, 0.1, -0.6, 0.2, 0.4, -0.5, 0.9, 0.3, -0.1, 0.8, 0.2, -0.7),
+		"fc2.bias":   mat(1, 5, 0.05, -0.05, 0.1, 0, -0.1),
+		"fc3.weight": mat(5, 2, 1, -1, -0.5, 0.5, 0.25, 0.75, -0.8, 0.2, 0.6, -0.4),
+	}
+	x := mat(4, 3, 0, 0, 0, 1, 2, 3, -1.5, 0.5, 2.5, 0.3, -0.7, -1.2)
+
+	get := func(m math.Mat[float32], i, j int) float64 { return float64(m.Data[i*m.Col+j]) }
+	gelu := func(v float64) float64 { return 0.5 * v * (1 + stdmath.Erf(v/stdmath.Sqrt2)) }
+	geluTanh := func(v float64) float64 {
+		return 0.5 * v * (1 + stdmath.Tanh(stdmath.Sqrt(2/stdmath.Pi)*(v+0.044715*v*v*v)))
+	}
+
+	out := math.Zeros[float32](4, 2)
+	for s := 0; s < 4; s++ {
+		h := make([]float64, 4)
+		for o := range h {
+			h[o] = get(w["fc1.bias"], 0, o)
+			for i := 0; i < 3; i++ {
+				h[o] += get(x, s, i) * get(w["fc1.weight"], o, i)
+			}
+			h[o] = gelu(h[o])
+		}
+		mean, vr := 0.0, 0.0
+		for _, v := range h {
+			mean += v / 4
+		}
+		for _, v := range h {
+			vr += (v - mean) * (v - mean) / 4
+		}
+		for o := range h {
+			h[o] = (h[o]-mean)/stdmath.Sqrt(vr+1e-5)*get(w["ln.weight"], 0, o) + get(w["ln.bias"], 0, o)
+		}
+		h2 := make([]float64, 5)
+		for o := range h2 {
+			h2[o] = get(w["fc2.bias"], 0, o)
+			for i := range h {
+				h2[o] += h[i] * get(w["fc2.weight"], i, o)
+			}
+			h2[o] = stdmath.Max(h2[o], 0)
+		}
+		z := make([]float64, 2)
+		for o := range z {
+			for i := range h2 {
+				z[o] += h2[i] * get(w["fc3.weight"], i, o)
+			}
+			z[o] = geluTanh(z[o])
+		}
+		mx := stdmath.Max(z[0], z[1])
+		e0, e1 := stdmath.Exp(z[0]-mx), stdmath.Exp(z[1]-mx)
+		out.Data[s*2] = float32(e0 / (e0 + e1))
+		out.Data[s*2+1] = float32(e1 / (e0 + e1))
+	}
+
+	write := func(path string, arrays map[string]math.Mat[float32]) {
+		f, err := os.Create(path)
+		if err != nil {
+			panic(err)
+		}
+		if err := math.WriteNPZ(f, arrays); err != nil {
+			panic(err)
+		}
+		if err := f.Close(); err != nil {
+			panic(err)
+		}
+	}
+	write("testdata/mlp/weights.npz", w)
+	write("testdata/mlp/golden.npz", map[string]math.Mat[float32]{"input": x, "output": out})
+}